@@ -1,24 +1,47 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"go/types"
+	"io"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
-	"github.com/iancoleman/strcase"
 	"github.com/refaktor/ryegen/v2/config"
 	"github.com/refaktor/ryegen/v2/converter"
 	"github.com/refaktor/ryegen/v2/converter/typeset"
+	"github.com/refaktor/ryegen/v2/textutils"
 )
 
-const builtinsCommonCode = `import (
+// builtinsCommonCode returns the helper code of the builtins
+// file. Unless library is set, it includes a main func running
+// a Rye interpreter with the bindings.
+func builtinsCommonCode(library bool) string {
+	if library {
+		return builtinsImports + builtinsHelpers
+	}
+	return builtinsImports + builtinsMainImports + builtinsHelpers + builtinsMain
+}
+
+// Imports used by builtinsHelpers and the builtins.
+const builtinsImports = `import (
+	_fmt "fmt"
+	_os "os"
+	_sync "sync"
+
 	_env "github.com/refaktor/rye/env"
 	_evaldo "github.com/refaktor/rye/evaldo"
-	_runner "github.com/refaktor/rye/runner"
 )
+`
+
+const builtinsMainImports = `import _runner "github.com/refaktor/rye/runner"
+`
 
+const builtinsHelpers = `
 func mustBuiltin(x _env.VarBuiltin, err error) *_env.VarBuiltin {
 	if err != nil {
 		panic(err)
@@ -26,6 +49,26 @@ func mustBuiltin(x _env.VarBuiltin, err error) *_env.VarBuiltin {
 	return &x
 }
 
+func withDoc(x *_env.VarBuiltin, doc string) *_env.VarBuiltin {
+	x.Doc = doc
+	return x
+}
+
+// deprecatedAlias returns a copy of x that prints a warning
+// to stderr the first time it is called.
+func deprecatedAlias(x *_env.VarBuiltin, alias, name string) *_env.VarBuiltin {
+	res := *x
+	var once _sync.Once
+	res.Fn = func(ps *_env.ProgramState, args ..._env.Object) _env.Object {
+		once.Do(func() {
+			_fmt.Fprintf(_os.Stderr, "Warning: %v is deprecated, use %v instead\n", alias, name)
+		})
+		return x.Fn(ps, args...)
+	}
+	res.Doc = "Deprecated: alias of " + name + "\n" + x.Doc
+	return &res
+}
+
 func builtinsContext(ps *_env.ProgramState, builtins map[string]*_env.VarBuiltin, name string) *_env.RyeCtx {
 	ctx := ps.Ctx
 	ps.Ctx = _env.NewEnv(ps.Ctx)
@@ -37,48 +80,148 @@ func builtinsContext(ps *_env.ProgramState, builtins map[string]*_env.VarBuiltin
 	return newctx
 }
 
-var packages = map[string]*_env.RyeCtx{}
+var (
+	// Funcs adding the builtins of each Go package to a map,
+	// see addBuiltins
+	builtinInits = map[string][]func(m map[string]*_env.VarBuiltin){}
+	// Initialized builtins of each Go package
+	builtins   = map[string]map[string]*_env.VarBuiltin{}
+	builtinsMu _sync.Mutex
+)
 
-func main() {
-	_runner.DoMain(func(ps *_env.ProgramState) error {
-		for pkg, builtins := range builtins {
-			packages[pkg] = builtinsContext(ps, builtins, "gopkg(" + pkg + ")")
+// addBuiltins adds a func adding builtins of the Go package
+// pkg to a map. The funcs of a package only run the first time
+// its builtins are needed. init may be nil to only declare the
+// package.
+func addBuiltins(pkg string, init func(m map[string]*_env.VarBuiltin)) {
+	builtinInits[pkg] = append(builtinInits[pkg], init)
+}
+
+// builtinsOf returns the builtins of the Go package pkg,
+// initializing them on first use.
+func builtinsOf(pkg string) (map[string]*_env.VarBuiltin, bool) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	if m, ok := builtins[pkg]; ok {
+		return m, true
+	}
+	inits, ok := builtinInits[pkg]
+	if !ok {
+		return nil, false
+	}
+	m := map[string]*_env.VarBuiltin{}
+	for _, init := range inits {
+		if init != nil {
+			init(m)
 		}
-		_evaldo.RegisterVarBuiltins2(map[string]*_env.VarBuiltin{
-			"nil": {
-				Argsn: 0,
-				Fn: func(ps *_env.ProgramState, _ ..._env.Object) _env.Object {
-					return *_env.NewVoid()
-				},
+	}
+	builtins[pkg] = m
+	return m, true
+}
+
+// Register registers the bindings in ps. Each Go package is
+// available via import\go, which initializes the package's
+// builtins the first time it is imported. Bindings generated
+// into other packages can be registered in the same ps. If
+// several of them have the same Go package, their builtins
+// are merged, keeping those registered first for words they
+// have in common.
+func Register(ps *_env.ProgramState) {
+	root := ps.Ctx
+	// Packages not in this binding set are looked
+	// up by bindings registered before
+	var prevImport *_env.VarBuiltin
+	if idx, ok := ps.Idx.GetIndex("import\\go"); ok {
+		if obj, ok := root.Get(idx); ok {
+			if b, ok := obj.(_env.VarBuiltin); ok {
+				prevImport = &b
+				root.Unset(idx, ps.Idx)
+			}
+		}
+	}
+	_evaldo.RegisterVarBuiltins2(map[string]*_env.VarBuiltin{
+		"nil": {
+			Argsn: 0,
+			Fn: func(ps *_env.ProgramState, _ ..._env.Object) _env.Object {
+				return *_env.NewVoid()
 			},
-			"is-nil": {
-				Argsn: 1,
-				Fn: func(ps *_env.ProgramState, objs ..._env.Object) _env.Object {
-					_, ok := objs[0].(_env.Void)
-					return *_env.NewBoolean(ok)
-				},
+		},
+		"is-nil": {
+			Argsn: 1,
+			Fn: func(ps *_env.ProgramState, objs ..._env.Object) _env.Object {
+				_, ok := objs[0].(_env.Void)
+				return *_env.NewBoolean(ok)
 			},
-			"import\\go": {
-				Argsn: 1,
-				Fn: func(ps *_env.ProgramState, args ..._env.Object) _env.Object {
-					arg0, ok := args[0].(_env.String)
-					if !ok {
-						ps.FailureFlag = true
-						return _env.NewError("expected package name string, but got " + objectType(ps, args[0]))
+		},
+		"import\\go": {
+			Argsn: 1,
+			Fn: func(ps *_env.ProgramState, args ..._env.Object) _env.Object {
+				arg0, ok := args[0].(_env.String)
+				if !ok {
+					ps.FailureFlag = true
+					return _env.NewError("expected package name string, but got " + objectType(ps, args[0]))
+				}
+				name := "gopkg(" + arg0.Value + ")"
+				if idx, found := ps.Idx.GetIndex(name); found {
+					if pkg, found := root.Get(idx); found {
+						return pkg
 					}
-					pkg, ok := packages[arg0.Value]
-					if !ok {
-						ps.FailureFlag = true
-						return _env.NewError("unknown Go package \"" + arg0.Value + "\"")
+				}
+				// Bindings registered before may have the package
+				// too, in which case their builtins are merged
+				var prev _env.Object
+				if prevImport != nil {
+					prev = prevImport.Fn(ps, args...)
+					if _, failed := prev.(*_env.Error); failed {
+						ps.FailureFlag = false
+						prev = nil
+					}
+				}
+				m, ok := builtinsOf(arg0.Value)
+				if !ok {
+					if prev != nil {
+						return prev
 					}
-					return *pkg
-				},
+					ps.FailureFlag = true
+					return _env.NewError("unknown Go package \"" + arg0.Value + "\"")
+				}
+				ctx := ps.Ctx
+				ps.Ctx = root
+				var pkg *_env.RyeCtx
+				if prevPkg, ok := prev.(_env.RyeCtx); ok {
+					// Words already set by the bindings registered
+					// before are kept
+					pkg = &prevPkg
+					ps.Ctx = pkg
+					_evaldo.RegisterVarBuiltins2(m, ps, name)
+				} else {
+					pkg = builtinsContext(ps, m, name)
+				}
+				ps.Ctx = ctx
+				return *pkg
 			},
-		}, ps, "base")
+		},
+	}, ps, "base")
+}
+
+// Builtins returns the builtins of the bindings by Go
+// package path. All builtins are initialized.
+func Builtins() map[string]map[string]*_env.VarBuiltin {
+	res := map[string]map[string]*_env.VarBuiltin{}
+	for pkg := range builtinInits {
+		res[pkg], _ = builtinsOf(pkg)
+	}
+	return res
+}
+`
+
+const builtinsMain = `
+func main() {
+	_runner.DoMain(func(ps *_env.ProgramState) error {
+		Register(ps)
 		return nil
 	})
 }
-
 `
 
 type bindingType int
@@ -124,10 +267,18 @@ func bindingTypeFromString(s string) (bindingType, bool) {
 }
 
 type bindingProperties struct {
-	pkgPath string // package path in Rye
-	recv    string // receiver type in Rye
-	name    string // binding name in Rye
-	exclude bool   // true -> don't generate
+	pkgPath string         // package path in Rye
+	recv    string         // receiver type in Rye
+	name    string         // binding name in Rye
+	aliases []bindingAlias // additional names in Rye
+	exclude bool           // true -> don't generate
+}
+
+// bindingAlias is an additional name of a binding
+// (in the same package and with the same receiver).
+type bindingAlias struct {
+	name       string
+	deprecated bool // warn on first use at runtime
 }
 
 type binding struct {
@@ -138,6 +289,10 @@ type binding struct {
 	typ bindingType
 	// Go receiver type for textual filtering (without ptrs and struct without aliases)
 	recv string
+	// Go parameter types (without receiver) for textual filtering, separated by ", "
+	params string
+	// Go result types for textual filtering, separated by ", "
+	results string
 	// Package of the func/type
 	pkg *types.Package
 	// A converter to Rye for this signature type is required
@@ -145,6 +300,12 @@ type binding struct {
 	requiredConverter *types.Signature
 	// Imports required by the binding code (order and element uniqueness not guaranteed)
 	funcCodeImports []*types.Package
+	// Fully qualified Go symbol, e.g. "(*net/http.Client).Do"
+	goSymbol string
+	// Short Go declaration of the bound symbol, e.g. "func (*Client).Do(*Request) (*Response, error)"
+	goDecl string
+	// Go doc comment of the bound symbol (may be empty)
+	doc string
 
 	// Binding properties. Data in here is what's mutated by binding rules.
 	props bindingProperties
@@ -160,23 +321,168 @@ func (b *binding) fillPropsAndRecv(bName string, tset *typeset.TypeSet) {
 	if b.pkg != nil {
 		b.props.pkgPath = b.pkg.Path()
 	}
+	b.params = tupleTypeString(b.requiredConverter.Params(), tset)
+	b.results = tupleTypeString(b.requiredConverter.Results(), tset)
 	if b.requiredConverter.Recv() != nil {
 		b.props.recv =
 			converter.ReceiverRyeTypeName(b.requiredConverter.Recv().Type(), tset)
 		b.recv = recvTypeNameForTextualFiltering(b.requiredConverter.Recv().Type())
 	}
 }
+
+// conflictSuffix returns the name suffix used to resolve
+// naming conflicts with the given suffix-* policy.
+// Falls back to the binding type if the binding has
+// no package or named receiver.
+func (bf *binding) conflictSuffix(policy string) string {
+	switch policy {
+	case config.OnConflictSuffixPackage:
+		if bf.pkg != nil {
+			return bf.pkg.Name()
+		}
+	case config.OnConflictSuffixRecv:
+		if recv := bf.requiredConverter.Recv(); recv != nil {
+			t := recv.Type()
+			for {
+				if pt, ok := t.(*types.Pointer); ok {
+					t = pt.Elem()
+				} else {
+					break
+				}
+			}
+			switch t := t.(type) {
+			case *types.Named:
+				return t.Obj().Name()
+			case *types.Alias:
+				return t.Obj().Name()
+			}
+		}
+	}
+	return bf.typ.String()
+}
+
+// withNameSuffix appends a backslash and suffix to the Rye
+// name, keeping a trailing "?" or "!" at the end, e.g.
+// withNameSuffix("name?", "pkg") = `name\pkg?`.
+func withNameSuffix(name, suffix string) string {
+	if base, ok := strings.CutSuffix(name, "?"); ok {
+		return base + `\` + suffix + "?"
+	}
+	if base, ok := strings.CutSuffix(name, "!"); ok {
+		return base + `\` + suffix + "!"
+	}
+	return name + `\` + suffix
+}
+
+// tupleTypeString returns the types in tup separated by ", ".
+func tupleTypeString(tup *types.Tuple, tset *typeset.TypeSet) string {
+	var b strings.Builder
+	for i := range tup.Len() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(tset.TypeString(tup.At(i).Type()))
+	}
+	return b.String()
+}
+
+// returnsError returns whether the last result of the
+// binding's Go function is an error.
+func (bf *binding) returnsError() bool {
+	res := bf.requiredConverter.Results()
+	return res.Len() > 0 &&
+		types.Identical(res.At(res.Len()-1).Type(), types.Universe.Lookup("error").Type())
+}
+
 func (bf *binding) key() string {
+	return bf.props.key()
+}
+
+func (p bindingProperties) key() string {
+	return p.keyWithName(p.name)
+}
+
+// keyWithName returns the key the binding would have if
+// it was named name (used for aliases).
+func (p bindingProperties) keyWithName(name string) string {
 	var b strings.Builder
-	if bf.props.recv != "" {
-		fmt.Fprintf(&b, "%v//", bf.props.recv)
+	if p.recv != "" {
+		fmt.Fprintf(&b, "%v//", p.recv)
 	}
-	fmt.Fprintf(&b, "%v", bf.props.name)
+	fmt.Fprintf(&b, "%v", name)
 	return b.String()
 }
 
-func (bf *binding) binding(convName string) string {
-	return fmt.Sprintf("mustBuiltin(%v(nil, %v))", convName, bf.funcCode)
+// hasName returns whether name is the binding's name or an alias.
+func (p bindingProperties) hasName(name string) bool {
+	return name == p.name || slices.ContainsFunc(p.aliases, func(a bindingAlias) bool {
+		return a.name == name
+	})
+}
+
+// String returns the properties in a human-readable form,
+// e.g. "net/http::go(*net_http.Client)//Do".
+func (p bindingProperties) String() string {
+	s := p.pkgPath + "::" + p.key()
+	for i, a := range p.aliases {
+		if i == 0 {
+			s += " (aliases: "
+		} else {
+			s += ", "
+		}
+		s += a.name
+		if a.deprecated {
+			s += " [deprecated]"
+		}
+		if i == len(p.aliases)-1 {
+			s += ")"
+		}
+	}
+	if p.exclude {
+		s += " (excluded)"
+	}
+	return s
+}
+
+// ryePkg returns the Rye package the binding is registered in.
+func (bf *binding) ryePkg() string {
+	if bf.props.pkgPath == "" {
+		// Special pseudo-package for bindings that may not be
+		// package-specific, e.g. struct aliases.
+		return "zz_global"
+	}
+	return bf.props.pkgPath
+}
+
+// aliasCode returns the Go statement adding alias to the
+// builtins map m, given the builtin has already been added
+// under the binding's key.
+func (bf *binding) aliasCode(alias bindingAlias) string {
+	aliasKey := bf.props.keyWithName(alias.name)
+	if alias.deprecated {
+		return fmt.Sprintf(`m[%q] = deprecatedAlias(m[%q], %q, %q)`, aliasKey, bf.key(), alias.name, bf.props.name)
+	}
+	return fmt.Sprintf(`m[%q] = m[%q]`, aliasKey, bf.key())
+}
+
+// binding returns the Go expression for the builtin.
+// If withDoc is true, the builtin carries the Go declaration
+// and the first paragraph of the doc comment as its doc string.
+func (bf *binding) binding(convName string, withDoc bool) string {
+	code := fmt.Sprintf("mustBuiltin(%v(nil, %v))", convName, bf.funcCode)
+	if withDoc && bf.goDecl != "" {
+		code = fmt.Sprintf("withDoc(%v, %v)", code, strconv.Quote(bf.builtinDoc()))
+	}
+	return code
+}
+
+// builtinDoc returns the doc string of the Rye builtin.
+func (bf *binding) builtinDoc() string {
+	doc := textutils.FirstParagraph(bf.doc)
+	if doc == "" {
+		return bf.goDecl
+	}
+	return bf.goDecl + "\n" + doc
 }
 
 // Subset of bindingProperties
@@ -198,19 +504,132 @@ type bindingSet struct {
 	initialProps []bindingProperties
 
 	invalid bool
+
+	// Number of bindings selected by each rule
+	ruleMatches map[*config.Rule]int
+
+	// If non-nil, the rules applied to each binding whose Go
+	// symbol or Rye name (before or after applying the rules,
+	// formatted as in [bindingProperties.String]) matches
+	// explain are written to explainW.
+	explain  *regexp.Regexp
+	explainW io.Writer
+
+	// Descriptions of all naming conflicts that were
+	// automatically resolved (see [config.Config.OnConflict])
+	conflictResolutions []string
+
+	// Initial Rye receiver name to the name set by a
+	// to-casing rule; also the native kind names to be
+	// used by converters (see [converter.ConverterSet.SetNativeKindNames])
+	recvNames map[string]string
 }
 
+// renameRecv renames the Rye receiver name initialRecv
+// of all bindings to newRecv.
+func (bs *bindingSet) renameRecv(initialRecv, newRecv string) error {
+	if prev, ok := bs.recvNames[initialRecv]; ok && prev == newRecv {
+		return nil
+	}
+	for other, name := range bs.recvNames {
+		if name == newRecv && other != initialRecv {
+			return fmt.Errorf("to-casing: renaming receiver %v to %v would cause naming conflict with receiver %v", initialRecv, newRecv, other)
+		}
+	}
+	if newRecv != initialRecv {
+		for i, props := range bs.initialProps {
+			if props.recv == newRecv {
+				if _, renamed := bs.recvNames[props.recv]; !renamed {
+					return fmt.Errorf("to-casing: renaming receiver %v to %v would cause naming conflict with receiver of %v", initialRecv, newRecv, bs.bindings[i].goSymbol)
+				}
+			}
+		}
+	}
+	if bs.recvNames == nil {
+		bs.recvNames = map[string]string{}
+	}
+	bs.recvNames[initialRecv] = newRecv
+	return nil
+}
+
+// recvToCasing converts a Rye receiver name to the given casing,
+// leaving the "go(...)" wrapper of Go-native types intact, e.g.
+// recvToCasing("go(*net_http.Client)", "kebab", false) = "go(*net-http.client)".
+func recvToCasing(recv, casing string, splitDigits bool) string {
+	if inner, ok := strings.CutPrefix(recv, "go("); ok {
+		if inner, ok := strings.CutSuffix(inner, ")"); ok {
+			res, _ := textutils.ToCasing(inner, casing, splitDigits)
+			return "go(" + res + ")"
+		}
+	}
+	res, _ := textutils.ToCasing(recv, casing, splitDigits)
+	return res
+}
+
+// pkgPathToCasing converts each element of the package path
+// pkgPath to the given casing, keeping major version elements
+// like "v2" as they are.
+func pkgPathToCasing(pkgPath, casing string, splitDigits bool) string {
+	elems := strings.Split(pkgPath, "/")
+	for i, elem := range elems {
+		if reMajorVersion.MatchString(elem) {
+			continue
+		}
+		elems[i], _ = textutils.ToCasing(elem, casing, splitDigits)
+	}
+	return strings.Join(elems, "/")
+}
+
+var reMajorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
 func newBindingSet() *bindingSet {
 	return &bindingSet{
 		currentIdx: map[bindingSymbol]int{},
 	}
 }
 
+// ruleApplication is a single rule that selected a binding.
+// Used for explaining.
+type ruleApplication struct {
+	rule     *config.Rule
+	backrefs []string
+	result   bindingProperties // properties after applying the rule
+}
+
+// writeExplanation writes how the binding with the given initial
+// properties ended up with its final properties.
+func (bs *bindingSet) writeExplanation(bf binding, initial bindingProperties, apps []ruleApplication) {
+	w := bs.explainW
+	fmt.Fprintf(w, "explain %v:\n", cmp.Or(bf.goSymbol, bf.funcCode))
+	fmt.Fprintf(w, "  initial: %v\n", initial)
+	fmt.Fprintf(w, "  selectable as: package=%q", initial.pkgPath)
+	if bf.recv != "" {
+		fmt.Fprintf(w, " recv=%q", bf.recv)
+	}
+	fmt.Fprintf(w, " name=%q type=%q params=%q results=%q variadic=%v returns-error=%v deprecated=%v\n",
+		initial.name, bf.typ, bf.params, bf.results, bf.requiredConverter.Variadic(), bf.returnsError(), textutils.IsDeprecated(bf.doc))
+	for _, app := range apps {
+		fmt.Fprintf(w, "  rule at %v", app.rule.Pos)
+		if len(app.backrefs) > 0 {
+			fmt.Fprintf(w, " with")
+			for i, br := range app.backrefs {
+				fmt.Fprintf(w, ` \%v=%q`, i+1, br)
+			}
+		}
+		fmt.Fprintf(w, "\n    -> %v\n", app.result)
+	}
+	if len(apps) == 0 {
+		fmt.Fprintf(w, "  no matching rules\n")
+	}
+	fmt.Fprintf(w, "  result: %v\n", bf.props)
+}
+
 // addWithRules adds a copy of the binding funcs to the bindingSet, applying
-// the renaming/exclusion rules in the config.
+// the given renaming/exclusion rules in order (see [config.Config.GlobalRules]).
+// c provides the remaining settings, like the naming conflict policy.
 // If any call to this function fails, the bindingSet is invalidated.
 // addedBindings is only valid until the next call of this function.
-func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindings []binding, err error) {
+func (bs *bindingSet) addWithRules(c *config.Config, rules []*config.Rule, bfs []binding) (addedBindings []binding, err error) {
 	defer func() {
 		if err != nil {
 			bs.invalid = true
@@ -243,6 +662,7 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 		renameRename renameUsage = iota
 		renameCasing
 		renamePkg
+		renameAlias // add newName as alias instead of renaming
 	)
 
 	// Backrefs represents the '\1', '\2' etc.,
@@ -253,7 +673,17 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 	// reduce memory allocations.
 	var backrefs [][]byte
 
-	for _, rule := range c.Rules {
+	if bs.ruleMatches == nil {
+		bs.ruleMatches = map[*config.Rule]int{}
+	}
+
+	// Binding index to rule applications; only used if bs.explain != nil
+	var applications map[int][]ruleApplication
+	if bs.explain != nil {
+		applications = map[int][]ruleApplication{}
+	}
+
+	for _, rule := range rules {
 		for _, bf := range bs.bindings[startIdx:] {
 			backrefs = backrefs[:0]
 			sym := bindingSymbol{bf.props.pkgPath, bf.recv, bf.props.name}
@@ -268,7 +698,7 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 			}
 			if rule.Select.Type != "" {
 				if _, ok := bindingTypeFromString(rule.Select.Type); !ok {
-					return nil, c.MakeError(rule.Select.TypePos, "select: unknown symbol type: %v (expected func, getter, setter or constructor)", rule.Select.Type)
+					return nil, rule.MakeError(rule.Select.TypePos, "select: unknown symbol type: %v (expected func, getter, setter or constructor)", rule.Select.Type)
 				}
 				if !strings.EqualFold(rule.Select.Type, bf.typ.String()) {
 					continue
@@ -288,8 +718,45 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 				}
 				backrefs = append(backrefs, m[1:]...)
 			}
+			if rule.Select.Params != nil {
+				m := rule.Select.Params.FindSubmatch([]byte(bf.params))
+				if len(m) == 0 || len(m[0]) != len(bf.params) {
+					continue
+				}
+				backrefs = append(backrefs, m[1:]...)
+			}
+			if rule.Select.Results != nil {
+				m := rule.Select.Results.FindSubmatch([]byte(bf.results))
+				if len(m) == 0 || len(m[0]) != len(bf.results) {
+					continue
+				}
+				backrefs = append(backrefs, m[1:]...)
+			}
+			if rule.Select.Variadic != nil && *rule.Select.Variadic != bf.requiredConverter.Variadic() {
+				continue
+			}
+			if rule.Select.ReturnsError != nil && *rule.Select.ReturnsError != bf.returnsError() {
+				continue
+			}
+			if rule.Select.Doc != nil {
+				m := rule.Select.Doc.FindSubmatch([]byte(bf.doc))
+				if m == nil {
+					continue
+				}
+				backrefs = append(backrefs, m[1:]...)
+			}
+			if rule.Select.Deprecated != nil && *rule.Select.Deprecated != textutils.IsDeprecated(bf.doc) {
+				continue
+			}
 
-			doRename := func(newName, newPkgPath string, usage renameUsage) error {
+			bs.ruleMatches[rule]++
+
+			doRename := func(newName, newPkgPath string, usage renameUsage, aliasDeprecated bool) error {
+				if bf.props.exclude {
+					// May have been excluded by a previous
+					// conflict resolution
+					return nil
+				}
 				if usage == renamePkg {
 					if newPkgPath == "" {
 						return fmt.Errorf("setting package would cause package path of (%v).%v to become empty, which is not allowed",
@@ -298,17 +765,34 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 					newName = bf.props.name // keep name
 				} else {
 					if newName == "" {
+						if usage == renameAlias {
+							return fmt.Errorf("alias of (%v).%v would be empty, which is not allowed",
+								bf.props.pkgPath, bf.props.name)
+						}
 						return fmt.Errorf("rename would cause name of (%v).%v to become empty, which is not allowed",
 							bf.props.pkgPath, bf.props.name)
 					}
 					newPkgPath = bf.props.pkgPath // keep pkg
 				}
-				if newName == bf.props.name && newPkgPath == bf.props.pkgPath {
+				if usage == renameAlias {
+					if bf.props.hasName(newName) {
+						return nil
+					}
+				} else if newName == bf.props.name && newPkgPath == bf.props.pkgPath {
 					return nil
 				}
 
-				newSym := bindingSymbol{newPkgPath, bf.recv, newName}
-				if conflictIdx, exists := bs.currentIdx[newSym]; exists && !bs.bindings[conflictIdx].props.exclude {
+				// conflictWith returns the index of the binding that
+				// newName would conflict with, or -1 if there is none.
+				conflictWith := func(newName string) int {
+					newSym := bindingSymbol{newPkgPath, bf.recv, newName}
+					conflictIdx, exists := bs.currentIdx[newSym]
+					if !exists || conflictIdx == bfIdx || bs.bindings[conflictIdx].props.exclude {
+						return -1
+					}
+					return conflictIdx
+				}
+				conflictError := func(newName string, conflictIdx int) error {
 					conflict := bs.bindings[conflictIdx]
 					var fullNewName string
 					if newPkgPath != bf.props.pkgPath {
@@ -326,26 +810,131 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 						conflict.props.pkgPath != init.pkgPath {
 						originallyText = fmt.Sprintf(" (originally (%v).%v)", init.pkgPath, init.name)
 					}
-					var errPfx string
+					var errPfx, errTo string
 					switch usage {
 					case renameRename:
-						errPfx = "renaming"
+						errPfx, errTo = "renaming", "to"
 					case renameCasing:
-						errPfx = "to-casing: renaming"
+						errPfx, errTo = "to-casing: renaming", "to"
 					case renamePkg:
-						errPfx = "setting package of"
+						errPfx, errTo = "setting package of", "to"
+					case renameAlias:
+						errPfx, errTo = "aliasing", "as"
+					}
+					if conflict.props.name != newName {
+						originallyText = fmt.Sprintf(" (alias of %v)", conflict.props.name) + originallyText
+					}
+					return fmt.Errorf("%v %v (%v).%v %v %v would cause naming conflict with %v %v%v",
+						errPfx, bf.typ, bf.props.pkgPath, bf.props.name, errTo, targetName, conflict.typ, fullNewName, originallyText)
+				}
+				// resolveConflict applies the on-conflict policy if newName
+				// (or alias newName, if isAlias is true) conflicts with
+				// another binding. Returns the name to use instead, or
+				// skip = true if the name should not be added.
+				resolveConflict := func(newName string, isAlias bool) (resolvedName string, skip bool, err error) {
+					conflictIdx := conflictWith(newName)
+					if conflictIdx == -1 {
+						return newName, false, nil
+					}
+					conflict := bs.bindings[conflictIdx]
+					policy := cmp.Or(c.OnConflict, config.OnConflictError)
+					report := func(format string, args ...any) {
+						bs.conflictResolutions = append(bs.conflictResolutions,
+							fmt.Sprintf("%v; on-conflict %v: %v", conflictError(newName, conflictIdx), policy, fmt.Sprintf(format, args...)))
+					}
+					var skipText string
+					if isAlias {
+						skipText = "dropped alias " + newName
+					} else {
+						skipText = fmt.Sprintf("excluded %v (%v).%v", bf.typ, bf.props.pkgPath, bf.props.name)
+					}
+					switch {
+					case policy == config.OnConflictError:
+						return "", false, conflictError(newName, conflictIdx)
+					case policy == config.OnConflictSkipLater:
+						report("%v", skipText)
+						return "", true, nil
+					case policy == config.OnConflictSuffixPackage || policy == config.OnConflictSuffixRecv:
+						suffixed := withNameSuffix(newName, bf.conflictSuffix(policy))
+						if conflictWith(suffixed) != -1 || (isAlias && bf.props.hasName(suffixed)) {
+							return "", false, fmt.Errorf("%w; on-conflict %v: suffixed name %v is also taken", conflictError(newName, conflictIdx), policy, suffixed)
+						}
+						report("used %v instead", suffixed)
+						return suffixed, false, nil
+					default: // prefer-<type>
+						preferred := strings.TrimPrefix(policy, config.OnConflictPreferPrefix)
+						if isAlias {
+							// Aliases never replace bindings
+							report("%v", skipText)
+							return "", true, nil
+						}
+						ours, theirs := strings.EqualFold(bf.typ.String(), preferred), strings.EqualFold(conflict.typ.String(), preferred)
+						if ours && !theirs {
+							report("excluded %v %v", conflict.typ, conflict.props.name)
+							excluded := conflict
+							excluded.props.exclude = true
+							bs.bindings[conflictIdx] = excluded
+							return newName, false, nil
+						} else if theirs && !ours {
+							report("%v", skipText)
+							return "", true, nil
+						}
+						return "", false, fmt.Errorf("%w; on-conflict %v: unable to resolve between two bindings of the same type", conflictError(newName, conflictIdx), policy)
 					}
-					return fmt.Errorf("%v %v (%v).%v to %v would cause naming conflict with %v %v%v",
-						errPfx, bf.typ, bf.props.pkgPath, bf.props.name, targetName, conflict.typ, fullNewName, originallyText)
 				}
 
 				newBf := bf
-				newBf.props.pkgPath = newSym.pkgPath
-				newBf.props.name = newSym.name
+				switch usage {
+				case renameAlias:
+					newName, skip, err := resolveConflict(newName, true)
+					if err != nil || skip {
+						return err
+					}
+					newBf.props.aliases = append(slices.Clip(bf.props.aliases), bindingAlias{
+						name:       newName,
+						deprecated: aliasDeprecated,
+					})
+					bs.currentIdx[bindingSymbol{newPkgPath, bf.recv, newName}] = bfIdx
+				default:
+					newName, skip, err := resolveConflict(newName, false)
+					if err != nil {
+						return err
+					}
+					if skip {
+						newBf.props.exclude = true
+						break
+					}
+					if usage == renamePkg {
+						// Aliases move with the binding
+						var movedAliases []bindingAlias
+						for _, a := range bf.props.aliases {
+							aliasName, skip, err := resolveConflict(a.name, true)
+							if err != nil {
+								return err
+							}
+							delete(bs.currentIdx, bindingSymbol{bf.props.pkgPath, bf.recv, a.name})
+							if !skip {
+								a.name = aliasName
+								movedAliases = append(movedAliases, a)
+								bs.currentIdx[bindingSymbol{newPkgPath, bf.recv, a.name}] = bfIdx
+							}
+						}
+						newBf.props.aliases = movedAliases
+					} else {
+						// Renaming to an alias turns it into the name
+						newBf.props.aliases = slices.DeleteFunc(slices.Clone(bf.props.aliases), func(a bindingAlias) bool {
+							return a.name == newName
+						})
+					}
+					newSym := bindingSymbol{newPkgPath, bf.recv, newName}
+					newBf.props.pkgPath = newSym.pkgPath
+					newBf.props.name = newSym.name
+					delete(bs.currentIdx, sym)
+					bs.currentIdx[newSym] = bfIdx
+					sym = newSym
+				}
 				bf = newBf
 				bs.bindings[bfIdx] = newBf
-				bs.currentIdx[newSym] = bfIdx
-				delete(bs.currentIdx, sym)
 
 				return nil
 			}
@@ -378,34 +967,90 @@ func (bs *bindingSet) addWithRules(c *config.Config, bfs []binding) (addedBindin
 			if !bf.props.exclude {
 				if rule.Actions.Rename != "" {
 					newName := substBackrefs(rule.Actions.Rename)
-					if err := doRename(newName, "", renameRename); err != nil {
-						return nil, c.MakeError(rule.Actions.RenamePos, "%v", err)
+					if err := doRename(newName, "", renameRename, false); err != nil {
+						return nil, rule.MakeError(rule.Actions.RenamePos, "%v", err)
 					}
 				}
 
-				if rule.Actions.ToCasing != "" {
-					var newName string
-					switch rule.Actions.ToCasing {
-					case "kebab":
-						newName = strcase.ToKebab(bf.props.name)
-					case "camel":
-						newName = strcase.ToCamel(bf.props.name)
-					case "snake":
-						newName = strcase.ToSnake(bf.props.name)
-					default:
-						return nil, c.MakeError(rule.Actions.ToCasingPos, "action: unknown casing: %v (expected kebab, camel or snake)", rule.Actions.ToCasing)
+				if casing := rule.Actions.ToCasing; casing != nil {
+					modePos, targetPos := casing.ModePos, casing.TargetPos
+					if casing.FromString {
+						modePos, targetPos = rule.Actions.ToCasingPos, rule.Actions.ToCasingPos
 					}
-					if err := doRename(newName, "", renameCasing); err != nil {
-						return nil, c.MakeError(rule.Actions.ToCasingPos, "%v", err)
+					if !slices.Contains(textutils.Casings, casing.Mode) {
+						return nil, rule.MakeError(modePos, "action: unknown casing: %v (expected %v)", casing.Mode, strings.Join(textutils.Casings, ", "))
+					}
+					switch casing.Target {
+					case "", "name":
+						newName, _ := textutils.ToCasing(bf.props.name, casing.Mode, casing.SplitsDigits())
+						if err := doRename(newName, "", renameCasing, false); err != nil {
+							return nil, rule.MakeError(modePos, "%v", err)
+						}
+					case "package":
+						newPkgPath := pkgPathToCasing(bf.props.pkgPath, casing.Mode, casing.SplitsDigits())
+						if err := doRename("", newPkgPath, renamePkg, false); err != nil {
+							return nil, rule.MakeError(modePos, "%v", err)
+						}
+					case "recv":
+						if bf.props.recv == "" {
+							break
+						}
+						newRecv := recvToCasing(bf.props.recv, casing.Mode, casing.SplitsDigits())
+						if err := bs.renameRecv(bs.initialProps[bfIdx].recv, newRecv); err != nil {
+							return nil, rule.MakeError(modePos, "%v", err)
+						}
+						bf.props.recv = newRecv
+						bs.bindings[bfIdx] = bf
+					default:
+						return nil, rule.MakeError(targetPos, "action: unknown casing target: %v (expected name, recv or package)", casing.Target)
 					}
 				}
 
 				if rule.Actions.SetPackage != "" {
 					newPkgPath := substBackrefs(rule.Actions.SetPackage)
-					if err := doRename("", newPkgPath, renamePkg); err != nil {
-						return nil, c.MakeError(rule.Actions.SetPackagePos, "%v", err)
+					if err := doRename("", newPkgPath, renamePkg, false); err != nil {
+						return nil, rule.MakeError(rule.Actions.SetPackagePos, "%v", err)
 					}
 				}
+
+				if rule.Actions.Alias != "" {
+					alias := substBackrefs(rule.Actions.Alias)
+					if err := doRename(alias, "", renameAlias, rule.Actions.AliasDeprecated); err != nil {
+						return nil, rule.MakeError(rule.Actions.AliasPos, "%v", err)
+					}
+				}
+			}
+
+			if applications != nil {
+				app := ruleApplication{
+					rule:   rule,
+					result: bs.bindings[bfIdx].props,
+				}
+				for _, br := range backrefs {
+					app.backrefs = append(app.backrefs, string(br))
+				}
+				applications[bfIdx] = append(applications[bfIdx], app)
+			}
+		}
+	}
+
+	if len(bs.recvNames) > 0 {
+		// Receiver names are shared by all bindings with the
+		// same receiver, including ones not selected by the rule
+		for i := range bs.bindings {
+			if name, ok := bs.recvNames[bs.initialProps[i].recv]; ok {
+				bs.bindings[i].props.recv = name
+			}
+		}
+	}
+
+	if bs.explain != nil {
+		for i := startIdx; i < len(bs.bindings); i++ {
+			bf, initial := bs.bindings[i], bs.initialProps[i]
+			if bs.explain.MatchString(bf.goSymbol) ||
+				bs.explain.MatchString(initial.String()) ||
+				bs.explain.MatchString(bf.props.String()) {
+				bs.writeExplanation(bf, initial, applications[i])
 			}
 		}
 	}
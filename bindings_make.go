@@ -11,7 +11,106 @@ import (
 	"github.com/refaktor/ryegen/v2/converter/typeset"
 )
 
-func makeFuncBinding(f *types.Func, tset *typeset.TypeSet) binding {
+// docIndex maps declared objects to their Go doc comments.
+type docIndex map[types.Object]string
+
+// add collects the doc comments of all top-level declarations,
+// methods, interface methods and struct fields in files.
+func (d docIndex) add(typesInfo *types.Info, files []*ast.File) {
+	addObj := func(ident *ast.Ident, docs ...*ast.CommentGroup) {
+		if !ident.IsExported() {
+			return
+		}
+		obj := typesInfo.ObjectOf(ident)
+		if obj == nil {
+			return
+		}
+		for _, doc := range docs {
+			if text := doc.Text(); text != "" {
+				d[obj] = text
+				return
+			}
+		}
+	}
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				addObj(name, field.Doc, field.Comment)
+			}
+		}
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				addObj(decl.Name, decl.Doc)
+			case *ast.GenDecl:
+				// A doc comment on an unparenthesized declaration
+				// belongs to its only spec.
+				var declDoc *ast.CommentGroup
+				if !decl.Lparen.IsValid() {
+					declDoc = decl.Doc
+				}
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						addObj(spec.Name, spec.Doc, declDoc, spec.Comment)
+						switch typ := spec.Type.(type) {
+						case *ast.StructType:
+							addFields(typ.Fields)
+						case *ast.InterfaceType:
+							addFields(typ.Methods)
+						}
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							addObj(name, spec.Doc, declDoc, spec.Comment)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// declQualifier qualifies all packages except pkg by their name.
+func declQualifier(pkg *types.Package) types.Qualifier {
+	return func(p *types.Package) string {
+		if pkg != nil && p.Path() == pkg.Path() {
+			return ""
+		}
+		return p.Name()
+	}
+}
+
+// funcDecl returns a short Go declaration of f without
+// parameter and result names, e.g. "func (*Client).Do(*Request) (*Response, error)".
+func funcDecl(f *types.Func) string {
+	unnamed := func(t *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, t.Len())
+		for i := range t.Len() {
+			vars[i] = types.NewParam(token.NoPos, nil, "", t.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+	sig := f.Signature()
+	var recv *types.Var
+	if sig.Recv() != nil {
+		recv = types.NewParam(token.NoPos, nil, "", sig.Recv().Type())
+	}
+	f = types.NewFunc(token.NoPos, f.Pkg(), f.Name(), types.NewSignatureType(
+		recv, nil, nil,
+		unnamed(sig.Params()),
+		unnamed(sig.Results()),
+		sig.Variadic(),
+	))
+	return types.ObjectString(f, declQualifier(f.Pkg()))
+}
+
+func makeFuncBinding(f *types.Func, doc string, tset *typeset.TypeSet) binding {
 	signature := f.Signature()
 
 	var bf binding
@@ -19,6 +118,9 @@ func makeFuncBinding(f *types.Func, tset *typeset.TypeSet) binding {
 	bf.pkg = f.Pkg()
 	bf.requiredConverter = signature
 	bf.funcCodeImports = []*types.Package{f.Pkg()}
+	bf.goSymbol = f.FullName()
+	bf.goDecl = funcDecl(f)
+	bf.doc = doc
 
 	var fun string
 	if signature.Recv() == nil {
@@ -48,7 +150,7 @@ func makeFuncBinding(f *types.Func, tset *typeset.TypeSet) binding {
 	return bf
 }
 
-func makeConstructorBinding(typ *types.Named, tset *typeset.TypeSet) binding {
+func makeConstructorBinding(typ *types.Named, docs docIndex, tset *typeset.TypeSet) binding {
 	signature := types.NewSignatureType(
 		nil, nil, nil,
 		types.NewTuple(types.NewVar(token.NoPos, nil, "", typ)),
@@ -64,12 +166,15 @@ func makeConstructorBinding(typ *types.Named, tset *typeset.TypeSet) binding {
 		pkg:               typ.Obj().Pkg(),
 		requiredConverter: signature,
 		funcCodeImports:   []*types.Package{typ.Obj().Pkg()},
+		goSymbol:          types.TypeString(typ, nil),
+		goDecl:            "type " + typ.Obj().Name(),
+		doc:               docs[typ.Obj()],
 	}
 	bf.fillPropsAndRecv(typ.Obj().Name(), tset)
 	return bf
 }
 
-func makeGetUnderlyingBinding(typ *types.Named, tset *typeset.TypeSet) binding {
+func makeGetUnderlyingBinding(typ *types.Named, docs docIndex, tset *typeset.TypeSet) binding {
 	under := typ.Underlying()
 	signature := types.NewSignatureType(
 		types.NewVar(token.NoPos, nil, "", typ),
@@ -87,12 +192,15 @@ func makeGetUnderlyingBinding(typ *types.Named, tset *typeset.TypeSet) binding {
 		pkg:               typ.Obj().Pkg(),
 		requiredConverter: signature,
 		funcCodeImports:   []*types.Package{typ.Obj().Pkg()},
+		goSymbol:          types.TypeString(typ, nil),
+		goDecl:            "type " + typ.Obj().Name(),
+		doc:               docs[typ.Obj()],
 	}
 	bf.fillPropsAndRecv("(?)", tset)
 	return bf
 }
 
-func makeFieldGetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
+func makeFieldGetterBindings(typ types.Type, docs docIndex, tset *typeset.TypeSet) []binding {
 	var pkg *types.Package
 	switch t := typ.(type) {
 	case *types.Alias:
@@ -145,6 +253,9 @@ func makeFieldGetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
 			pkg:               pkg,
 			requiredConverter: signature,
 			funcCodeImports:   requiredImports,
+			goSymbol:          types.TypeString(typ, nil) + "." + field.Name(),
+			goDecl:            fieldDecl(typ, field),
+			doc:               docs[field],
 		}
 		bf.fillPropsAndRecv(field.Name()+"?", tset)
 		bindings = append(bindings, bf)
@@ -152,7 +263,7 @@ func makeFieldGetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
 	return bindings
 }
 
-func makeFieldSetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
+func makeFieldSetterBindings(typ types.Type, docs docIndex, tset *typeset.TypeSet) []binding {
 	var pkg *types.Package
 	var objName string
 	switch t := typ.(type) {
@@ -204,6 +315,9 @@ func makeFieldSetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
 			pkg:               pkg,
 			requiredConverter: signature,
 			funcCodeImports:   requiredImports,
+			goSymbol:          types.TypeString(typ, nil) + "." + field.Name(),
+			goDecl:            fieldDecl(typ, field),
+			doc:               docs[field],
 		}
 		bf.fillPropsAndRecv(field.Name()+"!", tset)
 		bindings = append(bindings, bf)
@@ -211,7 +325,18 @@ func makeFieldSetterBindings(typ types.Type, tset *typeset.TypeSet) []binding {
 	return bindings
 }
 
-func makeGlobalGetterBinding(obj types.Object, tset *typeset.TypeSet) binding {
+// fieldDecl returns a short Go declaration of a struct
+// field, e.g. "field Client.Timeout time.Duration".
+func fieldDecl(structTyp types.Type, field *types.Var) string {
+	q := declQualifier(field.Pkg())
+	return fmt.Sprintf("field %v.%v %v",
+		types.TypeString(structTyp, q),
+		field.Name(),
+		types.TypeString(field.Type(), q),
+	)
+}
+
+func makeGlobalGetterBinding(obj types.Object, docs docIndex, tset *typeset.TypeSet) binding {
 	maybeAddrStr := ""
 	returnType := resolveUntyped(obj) // consts may be untyped
 	if _, ok := returnType.Underlying().(*types.Struct); ok {
@@ -235,13 +360,16 @@ func makeGlobalGetterBinding(obj types.Object, tset *typeset.TypeSet) binding {
 		),
 		pkg:               obj.Pkg(),
 		requiredConverter: signature,
-		funcCodeImports:   []*types.Package{obj.Pkg()},
+		funcCodeImports:   append([]*types.Package{obj.Pkg()}, collectImports(returnType)...),
+		goSymbol:          obj.Pkg().Path() + "." + obj.Name(),
+		goDecl:            types.ObjectString(obj, declQualifier(obj.Pkg())),
+		doc:               docs[obj],
 	}
 	bf.fillPropsAndRecv(obj.Name()+"?", tset)
 	return bf
 }
 
-func makeGlobalSetterBinding(obj types.Object, tset *typeset.TypeSet) binding {
+func makeGlobalSetterBinding(obj types.Object, docs docIndex, tset *typeset.TypeSet) binding {
 	signature := types.NewSignatureType(
 		nil, nil, nil,
 		types.NewTuple(types.NewVar(token.NoPos, nil, "", obj.Type())),
@@ -256,13 +384,16 @@ func makeGlobalSetterBinding(obj types.Object, tset *typeset.TypeSet) binding {
 		),
 		pkg:               obj.Pkg(),
 		requiredConverter: signature,
-		funcCodeImports:   []*types.Package{obj.Pkg()},
+		funcCodeImports:   append([]*types.Package{obj.Pkg()}, collectImports(obj.Type())...),
+		goSymbol:          obj.Pkg().Path() + "." + obj.Name(),
+		goDecl:            types.ObjectString(obj, declQualifier(obj.Pkg())),
+		doc:               docs[obj],
 	}
 	bf.fillPropsAndRecv(obj.Name()+"!", tset)
 	return bf
 }
 
-func makeMethodBindings(namedTyp *types.Named, tset *typeset.TypeSet) []binding {
+func makeMethodBindings(namedTyp *types.Named, docs docIndex, tset *typeset.TypeSet) []binding {
 	var bindings []binding
 	recvTyp := types.Type(namedTyp)
 	if iface, ok := namedTyp.Underlying().(*types.Interface); ok {
@@ -280,6 +411,7 @@ func makeMethodBindings(namedTyp *types.Named, tset *typeset.TypeSet) []binding
 		if !m.Exported() {
 			continue
 		}
+		doc := docs[m.Origin()]
 		{
 			// Receiver is currently the receiver the method was declared on.
 			// Set receiver to the current type we're actually binding methods for.
@@ -297,12 +429,15 @@ func makeMethodBindings(namedTyp *types.Named, tset *typeset.TypeSet) []binding
 			)
 		}
 
-		bindings = append(bindings, makeFuncBinding(m, tset))
+		bindings = append(bindings, makeFuncBinding(m, doc, tset))
 	}
 	return bindings
 }
 
-func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.File) []binding {
+// makePkgBindings creates all bindings for the package
+// represented by typesInfo and files. Any doc comments
+// are looked up in docs.
+func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.File, docs docIndex) []binding {
 	var bindings []binding
 	namedTypes := map[string]*types.Named{}
 	structAliasTypes := map[string]*types.Alias{}
@@ -320,7 +455,7 @@ func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.
 
 				f := typesInfo.ObjectOf(decl.Name).(*types.Func)
 				addStructAliasTypes(structAliasTypes, tset, tset.Normalized(f.Signature()))
-				bindings = append(bindings, makeFuncBinding(f, tset))
+				bindings = append(bindings, makeFuncBinding(f, docs[f], tset))
 			case *ast.GenDecl:
 				switch decl.Tok {
 				case token.TYPE:
@@ -346,7 +481,7 @@ func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.
 							for m := range namedTyp.Methods() {
 								addStructAliasTypes(structAliasTypes, tset, tset.Normalized(m.Signature()))
 							}
-							bindings = append(bindings, makeMethodBindings(namedTyp, tset)...)
+							bindings = append(bindings, makeMethodBindings(namedTyp, docs, tset)...)
 							namedTypes[spec.Name.Name] = typesInfo.ObjectOf(spec.Name).Type().(*types.Named)
 						}
 					}
@@ -359,9 +494,9 @@ func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.
 								}
 								obj := typesInfo.ObjectOf(name)
 								addStructAliasTypes(structAliasTypes, tset, tset.Normalized(obj.Type()))
-								bindings = append(bindings, makeGlobalGetterBinding(obj, tset))
+								bindings = append(bindings, makeGlobalGetterBinding(obj, docs, tset))
 								if decl.Tok == token.VAR {
-									bindings = append(bindings, makeGlobalSetterBinding(obj, tset))
+									bindings = append(bindings, makeGlobalSetterBinding(obj, docs, tset))
 								}
 							}
 						}
@@ -374,15 +509,15 @@ func makePkgBindings(tset *typeset.TypeSet, typesInfo *types.Info, files []*ast.
 	for _, typName := range slices.Sorted(maps.Keys(namedTypes)) {
 		typ := namedTypes[typName]
 
-		bindings = append(bindings, makeConstructorBinding(typ, tset))
-		bindings = append(bindings, makeGetUnderlyingBinding(typ, tset))
-		bindings = append(bindings, makeFieldGetterBindings(typ, tset)...)
-		bindings = append(bindings, makeFieldSetterBindings(typ, tset)...)
+		bindings = append(bindings, makeConstructorBinding(typ, docs, tset))
+		bindings = append(bindings, makeGetUnderlyingBinding(typ, docs, tset))
+		bindings = append(bindings, makeFieldGetterBindings(typ, docs, tset)...)
+		bindings = append(bindings, makeFieldSetterBindings(typ, docs, tset)...)
 	}
 	for _, name := range slices.Sorted(maps.Keys(structAliasTypes)) {
 		alias := structAliasTypes[name]
-		getters := makeFieldGetterBindings(alias, tset)
-		setters := makeFieldSetterBindings(alias, tset)
+		getters := makeFieldGetterBindings(alias, docs, tset)
+		setters := makeFieldSetterBindings(alias, docs, tset)
 		bindings = append(bindings, getters...)
 		bindings = append(bindings, setters...)
 	}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/converter/typeset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeBindingDocs(t *testing.T) {
+	require := require.New(t)
+
+	const src = `package main
+
+// Point is a point.
+type Point struct {
+	X, Y int
+	Name string
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	require.NoError(err)
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Uses:  map[*ast.Ident]types.Object{},
+		Defs:  map[*ast.Ident]types.Object{},
+	}
+	_, err = (&types.Config{}).Check("main", fset, []*ast.File{f}, info)
+	require.NoError(err)
+	docs := docIndex{}
+	docs.add(info, []*ast.File{f})
+
+	got := map[string]string{}
+	for _, bf := range makePkgBindings(typeset.New(nil), info, []*ast.File{f}, docs) {
+		got[bf.key()] = bf.builtinDoc()
+	}
+	// Only the type name, not the whole definition
+	require.Equal("type Point\nPoint is a point.", got["Point"])
+	require.Equal("type Point\nPoint is a point.", got["go(*main.Point)//(?)"])
+	require.Equal("field Point.Name string", got["go(*main.Point)//Name?"])
+}
+
+func TestPkgPathToCasing(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("fyne.io/fyne/v2", pkgPathToCasing("fyne.io/fyne/v2", "kebab", false))
+	require.Equal("Fyne.Io/Fyne/v2", pkgPathToCasing("fyne.io/fyne/v2", "camel", false))
+	require.Equal("FYNE.IO/FYNE/v2", pkgPathToCasing("fyne.io/fyne/v2", "screaming-snake", false))
+	require.Equal("golang.org/x/net/http2/h2c", pkgPathToCasing("golang.org/x/net/http2/h2c", "kebab", false))
+	require.Equal("golang.org/x/net/http2/h2c", pkgPathToCasing("golang.org/x/net/http2/h2c", "lower-camel", false))
+	require.Equal("my-pkg/sub-pkg", pkgPathToCasing("myPkg/sub_pkg", "kebab", false))
+	require.Equal("golang.org/x/net/http-2/h-2-c", pkgPathToCasing("golang.org/x/net/http2/h2c", "kebab", true))
+	require.Equal("fyne.io/fyne/v2", pkgPathToCasing("fyne.io/fyne/v2", "kebab", true))
+}
+
+func TestExplain(t *testing.T) {
+	require := require.New(t)
+
+	const src = `package main
+
+func NewClient() {}
+func NewServer() {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	require.NoError(err)
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Uses:  map[*ast.Ident]types.Object{},
+		Defs:  map[*ast.Ident]types.Object{},
+	}
+	_, err = (&types.Config{}).Check("main", fset, []*ast.File{f}, info)
+	require.NoError(err)
+	tset := typeset.New(func(p *types.Package) string { return p.Name() })
+	bindings := makePkgBindings(tset, info, []*ast.File{f}, docIndex{})
+
+	cfgPath := filepath.Join(t.TempDir(), "ryegen.toml")
+	require.NoError(os.WriteFile(cfgPath, []byte(`[[rule]]
+select.name = 'New(.*)'
+action.rename = 'make-\1'
+
+[[rule]]
+select.name = 'make-Server'
+action.include = false
+
+[[rule]]
+select.name = 'Never'
+action.include = false
+`), 0666))
+	cfg, err := config.Load(cfgPath)
+	require.NoError(err)
+
+	var explanation bytes.Buffer
+	bset := newBindingSet()
+	bset.explain = regexp.MustCompile("^main.Server$|NewServer")
+	bset.explainW = &explanation
+	_, err = bset.addWithRules(cfg, cfg.AllRules(), bindings)
+	require.NoError(err)
+	require.Equal(`explain main.NewServer:
+  initial: main::NewServer
+  selectable as: package="main" name="NewServer" type="func" params="" results="" variadic=false returns-error=false deprecated=false
+  rule at `+cfgPath+`:1:1 with \1="Server"
+    -> main::make-Server
+  rule at `+cfgPath+`:5:1
+    -> main::make-Server (excluded)
+  result: main::make-Server (excluded)
+`, explanation.String())
+
+	var usage ruleUsage
+	usage.add(cfg.AllRules(), bset.ruleMatches)
+	var log bytes.Buffer
+	usage.warnUnmatched(&Logger{Writer: &log})
+	require.Equal("WARNING: "+cfgPath+":9:1: rule never matched any binding\n", log.String())
+}
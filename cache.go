@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/converter"
+)
+
+// genCache holds the files written by the last run in a
+// directory, together with the key of the run's inputs (see
+// [genCacheKey]). If a run has the same key, it only has to
+// write the cached files instead of generating them.
+//
+// Otherwise, the outputs of the Go packages that didn't change
+// (see [pkgCacheKey]) are taken from the cache, so only the
+// bindings of changed packages and the packages importing them
+// are generated (see [targetGenerator]). There is a single
+// cache file per directory holding the entries of the last
+// run, so old entries don't pile up.
+type genCache struct {
+	Key   [sha256.Size]byte
+	Files map[string][]byte // path to contents
+	// Outputs of the Go packages by [pkgCacheKey], and the
+	// converter chunks they refer to by [chunkHash]
+	Packages map[[sha256.Size]byte]*pkgOutput
+	Chunks   map[[sha256.Size]byte]cachedChunk
+}
+
+// pkgOutput is the output of the bindings of a single Go
+// package of a target. It only depends on the package, the
+// packages it imports, the config, and the native kind names
+// of the converters.
+type pkgOutput struct {
+	// Imports used by the bindings, which are visited next
+	Visit []string
+	// Initial Rye receiver names of all bindings, and the
+	// names set by to-casing rules (see [bindingSet.recvNames])
+	Receivers []string
+	RecvNames map[string]string
+	// Default name to name of each native kind created
+	// by the converters, when they were generated (see
+	// [converter.ConverterSet.SetNativeKindNames])
+	KindNames map[string]string
+	// Number of bindings selected by each rule, by index
+	// in [config.Config.AllRules]
+	RuleMatches map[int]int
+	// See [bindingSet.conflictResolutions]
+	ConflictResolutions []string
+	Builtins            []cachedBuiltinsEntry
+	// Converter chunks the builtins depend on, by [chunkHash]
+	Chunks [][sha256.Size]byte
+	// Manifest entries of the builtins
+	Manifest []manifestBinding
+}
+
+// cachedBuiltinsEntry is a [builtinsEntry] in the cache.
+type cachedBuiltinsEntry struct {
+	Pkg, Key, Code string
+	Imports        []string
+}
+
+// cachedChunk is a [converter.Chunk] in the cache.
+type cachedChunk struct {
+	Key     string
+	Code    []byte
+	Init    bool
+	Imports [][2]string // path and name
+	Pkg     string
+}
+
+func newCachedBuiltinsEntry(e builtinsEntry) cachedBuiltinsEntry {
+	return cachedBuiltinsEntry{Pkg: e.pkg, Key: e.key, Code: e.code, Imports: e.imports}
+}
+
+func (e cachedBuiltinsEntry) entry() builtinsEntry {
+	return builtinsEntry{pkg: e.Pkg, key: e.Key, code: e.Code, imports: e.Imports}
+}
+
+func newCachedChunk(c converter.Chunk) cachedChunk {
+	res := cachedChunk{Key: c.Key, Code: c.Code, Init: c.Init, Pkg: c.Pkg}
+	for _, imp := range c.Imports {
+		res.Imports = append(res.Imports, [2]string{imp.Path(), imp.Name()})
+	}
+	return res
+}
+
+func (c cachedChunk) chunk() converter.Chunk {
+	res := converter.Chunk{Key: c.Key, Code: c.Code, Init: c.Init, Pkg: c.Pkg}
+	for _, imp := range c.Imports {
+		res.Imports = append(res.Imports, types.NewPackage(imp[0], imp[1]))
+	}
+	return res
+}
+
+// chunkHash identifies a converter chunk (see [converter.ChunkID]).
+func chunkHash(c converter.Chunk) [sha256.Size]byte {
+	return sha256.Sum256([]byte(converter.ChunkID(c)))
+}
+
+// genCachePath returns the path of the cache entry of the
+// current directory under the user cache dir.
+func genCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	dirHash := sha256.Sum256([]byte(wd))
+	return filepath.Join(cacheDir, "ryegen", hex.EncodeToString(dirHash[:16])+".gob"), nil
+}
+
+// loadGenCache loads the cache file at path. The files
+// are only loaded if the file has the given key, which
+// is reported by ok.
+func loadGenCache(path string, key [sha256.Size]byte) (_ genCache, ok bool, _ error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return genCache{}, false, nil
+		}
+		return genCache{}, false, err
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(f)
+	var res genCache
+	if err := dec.Decode(&res.Key); err != nil {
+		return genCache{}, false, err
+	}
+	if err := dec.Decode(&res.Packages); err != nil {
+		return genCache{}, false, err
+	}
+	if err := dec.Decode(&res.Chunks); err != nil {
+		return genCache{}, false, err
+	}
+	// Only decode the files if the key matches
+	if res.Key != key {
+		return res, false, nil
+	}
+	if err := dec.Decode(&res.Files); err != nil {
+		return genCache{}, false, err
+	}
+	return res, true, nil
+}
+
+// store writes the cache to path, replacing
+// the previous file atomically.
+func (c genCache) store(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	enc := gob.NewEncoder(f)
+	for _, v := range []any{c.Key, c.Packages, c.Chunks, c.Files} {
+		if err := enc.Encode(v); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ryegenBuildID identifies the build of the running Ryegen
+// executable: its module version, or the hash of the
+// executable for development builds and builds with
+// uncommitted changes.
+func ryegenBuildID() (string, error) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v := info.Main.Version
+		if v != "" && v != "(devel)" && !strings.HasSuffix(v, "+dirty") {
+			return v, nil
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// genInputsKey returns the key of the inputs of a run
+// besides the targets: the Ryegen build, the flags, the
+// config and the output package.
+func genInputsKey(cfg *config.Config, basePkg string) ([sha256.Size]byte, error) {
+	var res [sha256.Size]byte
+	buildID, err := ryegenBuildID()
+	if err != nil {
+		return res, err
+	}
+	h := sha256.New()
+	// The flags also end up in the generated files. Those that
+	// don't change them (e.g. -q) mustn't evict the entry.
+	fmt.Fprintf(h, "%v\n%q\n%x\n%v\n", buildID, headerArgs(os.Args[1:]), cfg.Hash, basePkg)
+	h.Sum(res[:0])
+	return res, nil
+}
+
+// genCacheKey returns the key of the inputs of a run
+// generating the bindings of targets (see [genCache]).
+// The sources of the targets must be resolved.
+func genCacheKey(cfg *config.Config, targets []targetConfig, basePkg string) ([sha256.Size]byte, error) {
+	var res [sha256.Size]byte
+	inputsKey, err := genInputsKey(cfg, basePkg)
+	if err != nil {
+		return res, err
+	}
+	h := sha256.New()
+	h.Write(inputsKey[:])
+	for _, tc := range targets {
+		fmt.Fprintf(h, "%v %x\n", tc.spec.name(), tc.deps.Hash)
+	}
+	h.Sum(res[:0])
+	return res, nil
+}
+
+// pkgCacheKey returns the key of the output of the Go package
+// at path (see [pkgOutput]), given the key of the run's other
+// inputs (see [genInputsKey]), the hash of the package and its
+// imports (see [loader.Sources.PackageHashes]), and the indices
+// of the rules applied to it in [config.Config.AllRules].
+func pkgCacheKey(inputsKey [sha256.Size]byte, path string, pkgHash [sha256.Size]byte, rules []int) [sha256.Size]byte {
+	var res [sha256.Size]byte
+	h := sha256.New()
+	fmt.Fprintf(h, "%x\n%v\n%x\n%v\n", inputsKey, path, pkgHash, rules)
+	h.Sum(res[:0])
+	return res
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/converter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenCache(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "ryegen", "cache.gob")
+	_, ok, err := loadGenCache(path, [32]byte{1})
+	require.NoError(err)
+	require.False(ok)
+
+	cache := genCache{
+		Key:   [32]byte{1},
+		Files: map[string][]byte{"ryegen_deps.gen.go": []byte("package main\n")},
+		Packages: map[[32]byte]*pkgOutput{{3}: {
+			Visit:    []string{"net/url"},
+			Builtins: []cachedBuiltinsEntry{{Pkg: "lib"}, {Pkg: "lib", Key: "parse", Code: "m[\"parse\"] = nil\n"}},
+			Chunks:   [][32]byte{{4}},
+		}},
+		Chunks: map[[32]byte]cachedChunk{{4}: {Key: "prelude", Code: []byte("// prelude\n")}},
+	}
+	require.NoError(cache.store(path))
+	loaded, ok, err := loadGenCache(path, [32]byte{1})
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(cache, loaded)
+
+	// The package outputs are loaded for other keys as well
+	loaded, ok, err = loadGenCache(path, [32]byte{2})
+	require.NoError(err)
+	require.False(ok)
+	require.Nil(loaded.Files)
+	require.Equal(cache.Packages, loaded.Packages)
+	require.Equal(cache.Chunks, loaded.Chunks)
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	c := converter.Chunk{
+		Key:     "typeLookup:net/url",
+		Code:    []byte("var _ url.URL\n"),
+		Imports: []*types.Package{types.NewPackage("net/url", "url")},
+		Pkg:     "net/url",
+	}
+	got := newCachedChunk(c).chunk()
+	require.Equal(t, converter.ChunkID(c), converter.ChunkID(got))
+	require.Equal(t, c.Imports[0].Path(), got.Imports[0].Path())
+	require.Equal(t, c.Imports[0].Name(), got.Imports[0].Name())
+}
+
+func TestGenCacheKeyFlags(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	key := func(args ...string) [32]byte {
+		os.Args = append([]string{"ryegen"}, args...)
+		k, err := genCacheKey(&config.Config{}, nil, "example.com/m")
+		require.NoError(t, err)
+		return k
+	}
+	// Flags that don't change the generated files
+	require.Equal(t, key("-goos", "linux"), key("-q", "-goos", "linux", "--v=true", "-no-cache"))
+	require.Equal(t, key("-goos", "linux"), key("-explain", "Client", "-goos", "linux", "-docs=ref", "-check"))
+	require.Equal(t, key("-goos", "linux"), key("-goos", "linux", "--docs", "ref", "-explain=."))
+	require.NotEqual(t, key("-goos", "linux"), key("-goos", "windows"))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// fileCheck collects the differences between the generated
+// files and the files on disk (see -check).
+type fileCheck struct {
+	diffs map[string]string // file name to summary of the difference
+}
+
+func newFileCheck() *fileCheck {
+	return &fileCheck{diffs: map[string]string{}}
+}
+
+// compare records whether the file name on disk differs from data.
+func (c *fileCheck) compare(name string, data []byte) error {
+	old, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.diffs[name] = "missing"
+			return nil
+		}
+		return err
+	}
+	if !bytes.Equal(old, data) {
+		c.diffs[name] = summarizeLineDiff(old, data)
+	}
+	return nil
+}
+
+// stale records that the generated file name on disk
+// would be deleted.
+func (c *fileCheck) stale(name string) {
+	c.diffs[name] = "stale, would be deleted"
+}
+
+func (c *fileCheck) ok() bool {
+	return len(c.diffs) == 0
+}
+
+func (c *fileCheck) write(w io.Writer) {
+	for _, name := range slices.Sorted(maps.Keys(c.diffs)) {
+		fmt.Fprintf(w, "%v: %v\n", name, c.diffs[name])
+	}
+}
+
+// summarizeLineDiff describes how b differs from a, e.g.
+// "differs from line 12 (+3 -1 lines)". The added and removed
+// lines are counted regardless of their position, which is
+// cheap even for huge files and exact for most edits.
+func summarizeLineDiff(a, b []byte) string {
+	lines := func(data []byte) [][]byte {
+		res := bytes.SplitAfter(data, []byte{'\n'})
+		if len(res[len(res)-1]) == 0 {
+			res = res[:len(res)-1]
+		}
+		return res
+	}
+	aLines, bLines := lines(a), lines(b)
+	first := 0
+	for first < len(aLines) && first < len(bLines) && bytes.Equal(aLines[first], bLines[first]) {
+		first++
+	}
+	count := map[string]int{}
+	for _, l := range aLines {
+		count[string(l)]++
+	}
+	var added, removed int
+	for _, l := range bLines {
+		if count[string(l)] > 0 {
+			count[string(l)]--
+		} else {
+			added++
+		}
+	}
+	for _, n := range count {
+		removed += n
+	}
+	return fmt.Sprintf("differs from line %v (+%v -%v lines)", first+1, added, removed)
+}
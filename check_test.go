@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeLineDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"a\nb\nc\n", "a\nB\nc\n", "differs from line 2 (+1 -1 lines)"},
+		{"a\nb\n", "a\nb\nc\nd\n", "differs from line 3 (+2 -0 lines)"},
+		{"a\nb\nc\n", "b\nc\n", "differs from line 1 (+0 -1 lines)"},
+		{"a\nb", "a\nb\n", "differs from line 2 (+1 -1 lines)"},
+		{"", "a\n", "differs from line 1 (+1 -0 lines)"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, summarizeLineDiff([]byte(tt.a), []byte(tt.b)), "%q -> %q", tt.a, tt.b)
+	}
+}
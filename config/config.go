@@ -2,15 +2,23 @@ package config
 
 import (
 	"bytes"
+	"cmp"
+	"crypto/sha256"
 	_ "embed"
 	"errors"
 	"fmt"
 	"go/build/constraint"
+	"go/token"
+	"iter"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"dario.cat/mergo"
 	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
 )
 
 type Constraint struct {
@@ -31,35 +39,121 @@ func (c *Constraint) UnmarshalText(text []byte) error {
 }
 
 type Target struct {
-	Select     *Constraint `toml:"select"`
-	CGoEnabled *bool       `toml:"cgo-enabled"`
+	Select *Constraint `toml:"select"`
+	// If both are set, the block declares a concrete target
+	// (generated with -all-targets), and only applies to it
+	GOOS   string `toml:"goos"`
+	GOARCH string `toml:"goarch"`
+	// Additional build tags of the concrete target
+	Tags       []string `toml:"tags"`
+	CGoEnabled *bool    `toml:"cgo-enabled"`
 }
 
 type Source struct {
-	Packages []string `toml:"packages"`
+	// Only load the source for targets satisfying the constraint
+	Select   *Constraint `toml:"select"`
+	Packages []string    `toml:"packages"`
+	// Rules that only apply to the source's packages
+	// (applied after the global rules)
+	Rules []Rule `toml:"rule"`
+	// Also apply Rules to all packages imported
+	// (directly or indirectly) by the source's packages
+	RulesIncludeDeps bool `toml:"rules-include-deps"`
+}
+
+// Casing is the to-casing rule action. It may either be given
+// as a table, or as a string, which is short for the mode.
+type Casing struct {
+	// E.g. "kebab" or "lower-camel"
+	Mode    string `toml:"mode"`
+	ModePos toml.FieldPosition
+	// What to convert: "name" (default), "recv" or "package"
+	Target    string `toml:"target"`
+	TargetPos toml.FieldPosition
+	// Whether digits are words of their own, e.g. "Int64" ->
+	// "int-64" (default: only for the name target, see
+	// SplitsDigits)
+	SplitDigits *bool `toml:"split-digits"`
+	// Set if given as a string; all positions are zero
+	FromString bool `toml:"-"`
+}
+
+// SplitsDigits returns whether digits are words of their own.
+// Unless set, they are for binding names, which is how names
+// were always converted, and aren't for receivers and package
+// paths, so e.g. "v2" and "http2" stay intact.
+func (c *Casing) SplitsDigits() bool {
+	if c.SplitDigits != nil {
+		return *c.SplitDigits
+	}
+	return c.Target == "" || c.Target == "name"
+}
+
+func (c *Casing) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		// Also the case for inline tables, which the decoder
+		// passes to UnmarshalText without any data
+		return errors.New("expected casing mode, or dotted keys mode and target")
+	}
+	*c = Casing{Mode: string(text), FromString: true}
+	return nil
+}
+
+// Position is a location in a config file.
+type Position struct {
+	File      string
+	Line, Col int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Col)
 }
 
 type Rule struct {
+	// Location of the rule's table header
+	Pos Position `toml:"-"`
+	// Same as [Config.MakeError], but for the file the rule
+	// was defined in (which may be an imported file)
+	MakeError toml.ErrorMaker `toml:"-"`
+
 	Select struct {
 		Package *regexp.Regexp `toml:"package"`
 		Name    *regexp.Regexp `toml:"name"`
 		Recv    *regexp.Regexp `toml:"recv"`
 		Type    string         `toml:"type"`
 		TypePos toml.FieldPosition
+		// Go parameter/result types, separated by ", "
+		Params       *regexp.Regexp `toml:"params"`
+		Results      *regexp.Regexp `toml:"results"`
+		Variadic     *bool          `toml:"variadic"`
+		ReturnsError *bool          `toml:"returns-error"`
+		// Go doc comment (not anchored)
+		Doc        *regexp.Regexp `toml:"doc"`
+		Deprecated *bool          `toml:"deprecated"`
 	} `toml:"select"`
 	Actions struct {
 		Include       *bool  `toml:"include"`
 		Rename        string `toml:"rename"`
 		RenamePos     toml.FieldPosition
-		ToCasing      string `toml:"to-casing"`
+		ToCasing      *Casing `toml:"to-casing"`
 		ToCasingPos   toml.FieldPosition
 		SetPackage    string `toml:"set-package"`
 		SetPackagePos toml.FieldPosition
+		Alias         string `toml:"alias"`
+		AliasPos      toml.FieldPosition
+		// Print a warning the first time the alias is used
+		AliasDeprecated bool `toml:"alias-deprecated"`
 	} `toml:"action"`
 }
 
 type Converter struct {
-	Type      *regexp.Regexp `toml:"type"`
+	Type *regexp.Regexp `toml:"type"`
+	// Convert the matched types using reflection at runtime
+	// instead of generated code
+	Reflect   bool `toml:"reflect"`
 	Templates struct {
 		ToRye   string `toml:"to-rye"`
 		FromRye string `toml:"from-rye"`
@@ -74,14 +168,114 @@ type ConverterHelper struct {
 	} `toml:"template"`
 }
 
+// Naming conflict policies (see [Config.OnConflict])
+const (
+	// Fail with an error
+	OnConflictError = "error"
+	// Exclude the binding that would take the name
+	OnConflictSkipLater = "skip-later"
+	// Suffix the name of the binding that would take the
+	// name with its Go package name
+	OnConflictSuffixPackage = "suffix-package"
+	// Suffix the name of the binding that would take the
+	// name with its Go receiver type name
+	OnConflictSuffixRecv = "suffix-recv"
+	// Followed by a binding type, e.g. "prefer-func":
+	// exclude whichever binding is not of that type
+	OnConflictPreferPrefix = "prefer-"
+)
+
+// Output configures the generated Go files.
+type Output struct {
+	// Directory to write the files to (default: ".")
+	Dir string `toml:"dir"`
+	// Go package name of the files (default: "main", or the
+	// base name of Dir if Library is set)
+	Package string `toml:"package"`
+	// Prefix of the file names (default: "ryegen_")
+	Prefix string `toml:"prefix"`
+	// Name of the file importing the source packages (default:
+	// prefix + "deps.gen.go"). Sources only selected for some
+	// targets are imported by per-target files named after it.
+	DepsFile string `toml:"deps-file"`
+	// Generate a package for use in another Rye interpreter,
+	// exporting Register and Builtins funcs instead of a main func
+	Library bool `toml:"library"`
+	// Split the generated code into one builtins and one
+	// converters file per Go package, which keeps incremental
+	// builds and editors responsive for large bindings
+	SplitPackages bool `toml:"split-packages"`
+}
+
+// PackageName returns the Go package name of the generated files.
+// In library mode without a package set, the name is taken from
+// Dir, which must then name a directory other than ".".
+func (o *Output) PackageName() (string, error) {
+	if o.Package != "" {
+		return o.Package, nil
+	}
+	if o.Library {
+		dir := filepath.Clean(o.Dir)
+		if dir == "." {
+			return "", errors.New("library mode needs a package name; please set the package or an output directory other than the current one")
+		}
+		return filepath.Base(dir), nil
+	}
+	return "main", nil
+}
+
+// FilePrefix returns the prefix of the generated file names.
+func (o *Output) FilePrefix() string {
+	return cmp.Or(o.Prefix, "ryegen_")
+}
+
+// DepsFileName returns the name of the file importing
+// the source packages.
+func (o *Output) DepsFileName() string {
+	return cmp.Or(o.DepsFile, o.FilePrefix()+"deps.gen.go")
+}
+
+// Check returns an error if the settings are invalid.
+func (o *Output) Check() error {
+	name, err := o.PackageName()
+	if err != nil {
+		return err
+	}
+	if !token.IsIdentifier(name) {
+		if o.Package == "" {
+			return fmt.Errorf("%q is not a valid Go package name; please set the package", name)
+		}
+		return fmt.Errorf("invalid Go package name: %v", name)
+	}
+	if (name == "main") == o.Library {
+		if o.Library {
+			return errors.New("library package can't be main")
+		}
+		return errors.New("package must be main unless library is set")
+	}
+	if strings.ContainsAny(o.Prefix, `/\`) {
+		return fmt.Errorf("file prefix must not contain path separators: %v", o.Prefix)
+	}
+	if strings.ContainsAny(o.DepsFile, `/\`) || o.DepsFile != "" && !strings.HasSuffix(o.DepsFile, ".go") {
+		return fmt.Errorf("deps file must be a file name ending with .go: %v", o.DepsFile)
+	}
+	return nil
+}
+
 type Config struct {
-	MakeError        toml.ErrorMaker   `toml:"-"`
-	Imports          []string          `toml:"imports"`
+	MakeError        toml.ErrorMaker `toml:"-"`
+	Imports          []string        `toml:"imports"`
+	Docs             *bool           `toml:"docs"`        // attach Go doc comments to builtins (default: true)
+	OnConflict       string          `toml:"on-conflict"` // naming conflict policy (default: "error")
+	OnConflictPos    toml.FieldPosition
 	Targets          []Target          `toml:"target"`
 	Sources          []Source          `toml:"source"`
 	Rules            []Rule            `toml:"rule"`
 	Converters       []Converter       `toml:"converter"`
 	ConverterHelpers []ConverterHelper `toml:"converter-helper"`
+	Output           Output            `toml:"output"`
+	// SHA-256 of the contents of the file and its imports
+	Hash [sha256.Size]byte `toml:"-"`
 }
 
 type Error struct {
@@ -147,6 +341,37 @@ func Load(path string) (_ *Config, err error) {
 	if err != nil {
 		return nil, err
 	}
+	switch c.OnConflict {
+	case "", OnConflictError, OnConflictSkipLater, OnConflictSuffixPackage, OnConflictSuffixRecv,
+		OnConflictPreferPrefix + "func", OnConflictPreferPrefix + "getter",
+		OnConflictPreferPrefix + "setter", OnConflictPreferPrefix + "constructor":
+	default:
+		return nil, c.MakeError(c.OnConflictPos, "unknown on-conflict policy: %v (expected error, skip-later, suffix-package, suffix-recv, or prefer- followed by func, getter, setter or constructor)", c.OnConflict)
+	}
+
+	globalPos, sourcePos := rulePositions(file)
+	setRulePositions := func(rules []*Rule, positions []unstable.Position) {
+		for i, rule := range rules {
+			rule.MakeError = c.MakeError
+			rule.Pos = Position{File: path}
+			if i < len(positions) {
+				rule.Pos.Line = positions[i].Line
+				rule.Pos.Col = positions[i].Column
+			}
+		}
+	}
+	setRulePositions(c.GlobalRules(), globalPos)
+	for i := range c.Sources {
+		var positions []unstable.Position
+		if i < len(sourcePos) {
+			positions = sourcePos[i]
+		}
+		rules := make([]*Rule, len(c.Sources[i].Rules))
+		for j := range c.Sources[i].Rules {
+			rules[j] = &c.Sources[i].Rules[j]
+		}
+		setRulePositions(rules, positions)
+	}
 
 	var importedCs []*Config // collect imported files first so their imports don't leak into our file's imports
 	for _, imp := range c.Imports {
@@ -156,11 +381,155 @@ func Load(path string) (_ *Config, err error) {
 		}
 		importedCs = append(importedCs, newC)
 	}
+	hash := sha256.New()
+	hash.Write(file)
 	for _, newC := range importedCs {
+		hash.Write(newC.Hash[:])
 		if err := mergo.Merge(c, newC, mergo.WithAppendSlice); err != nil {
 			return nil, err
 		}
 	}
+	hash.Sum(c.Hash[:0])
 
 	return c, nil
 }
+
+// ReflectTypes returns the type regexes of the converters
+// with reflect set.
+func (c *Config) ReflectTypes() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, conv := range c.Converters {
+		if conv.Reflect && conv.Type != nil {
+			res = append(res, conv.Type)
+		}
+	}
+	return res
+}
+
+// GlobalRules returns pointers to the rules that apply
+// to all packages.
+func (c *Config) GlobalRules() []*Rule {
+	res := make([]*Rule, len(c.Rules))
+	for i := range c.Rules {
+		res[i] = &c.Rules[i]
+	}
+	return res
+}
+
+// AllRules returns pointers to the global rules, followed
+// by the rules of each source.
+func (c *Config) AllRules() []*Rule {
+	return slices.AppendSeq(c.GlobalRules(), c.sourceRules())
+}
+
+func (c *Config) sourceRules() iter.Seq[*Rule] {
+	return func(yield func(*Rule) bool) {
+		for i := range c.Sources {
+			for j := range c.Sources[i].Rules {
+				if !yield(&c.Sources[i].Rules[j]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// rulePositions returns the positions of the global rules and of
+// each source's rules in the TOML document, in declaration order.
+// Rules may be defined using array table headers (e.g. "[[rule]]")
+// or inline tables (e.g. "rule = [{ ... }]"). Rules declared as
+// empty inline tables have a zero position.
+func rulePositions(document []byte) (global []unstable.Position, sources [][]unstable.Position) {
+	p := &unstable.Parser{}
+	p.Reset(document)
+
+	keyString := func(it unstable.Iterator) string {
+		var parts []string
+		for it.Next() {
+			parts = append(parts, string(it.Node().Data))
+		}
+		return strings.Join(parts, ".")
+	}
+	// keyPos returns the position of the key's first part, moved back
+	// to the given opening delimiter if it is on the same line.
+	keyPos := func(it unstable.Iterator, open string) unstable.Position {
+		if !it.Next() {
+			return unstable.Position{}
+		}
+		pos := p.Shape(it.Node().Raw).Start
+		line := document[pos.Offset-pos.Column+1 : pos.Offset]
+		if i := bytes.LastIndex(line, []byte(open)); i >= 0 {
+			pos.Column = i + 1
+		}
+		return pos
+	}
+	inlineRules := func(array *unstable.Node) []unstable.Position {
+		var res []unstable.Position
+		for it := array.Children(); it.Next(); {
+			table := it.Node()
+			if table.Kind != unstable.InlineTable {
+				continue
+			}
+			var pos unstable.Position
+			if kv := table.Child(); kv != nil {
+				pos = keyPos(kv.Key(), "{")
+			}
+			res = append(res, pos)
+		}
+		return res
+	}
+
+	var table string // key of the last table header
+	for p.NextExpression() {
+		expr := p.Expression()
+		key := keyString(expr.Key())
+		switch expr.Kind {
+		case unstable.Table:
+			table = key
+		case unstable.ArrayTable:
+			table = key
+			switch key {
+			case "rule":
+				global = append(global, keyPos(expr.Key(), "[["))
+			case "source":
+				sources = append(sources, nil)
+			case "source.rule":
+				if len(sources) > 0 {
+					sources[len(sources)-1] = append(sources[len(sources)-1], keyPos(expr.Key(), "[["))
+				}
+			}
+		case unstable.KeyValue:
+			value := expr.Value()
+			if value.Kind != unstable.Array {
+				continue
+			}
+			if table != "" {
+				key = table + "." + key
+			}
+			switch key {
+			case "rule":
+				global = append(global, inlineRules(value)...)
+			case "source.rule":
+				if len(sources) > 0 {
+					sources[len(sources)-1] = append(sources[len(sources)-1], inlineRules(value)...)
+				}
+			case "source":
+				for it := value.Children(); it.Next(); {
+					source := it.Node()
+					if source.Kind != unstable.InlineTable {
+						continue
+					}
+					var rules []unstable.Position
+					for kvs := source.Children(); kvs.Next(); {
+						kv := kvs.Node()
+						if keyString(kv.Key()) == "rule" && kv.Value().Kind == unstable.Array {
+							rules = inlineRules(kv.Value())
+						}
+					}
+					sources = append(sources, rules)
+				}
+			}
+		}
+	}
+	return global, sources
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulePositions(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	imported := filepath.Join(dir, "imported.toml")
+	require.NoError(os.WriteFile(imported, []byte(`rule = [
+  { select.name = 'a', action.include = false },
+  {},
+]
+`), 0o666))
+	main := filepath.Join(dir, "ryegen.toml")
+	require.NoError(os.WriteFile(main, []byte(`imports = ['`+imported+`']
+
+[[rule]]
+select.name = 'b'
+action.include = false
+
+[[source]]
+packages = ['example.com/one']
+rule = [{ select.name = 'c', action.include = false }]
+
+[[source]]
+packages = ['example.com/two']
+
+[[source.rule]]
+select.name = 'd'
+action.include = false
+
+[[rule]]
+select.name = 'e'
+action.include = false
+`), 0o666))
+
+	c, err := Load(main)
+	require.NoError(err)
+	var positions []string
+	for _, rule := range c.AllRules() {
+		positions = append(positions, rule.Pos.String())
+	}
+	require.Equal([]string{
+		main + ":3:1",
+		main + ":18:1",
+		imported + ":2:3",
+		imported,
+		main + ":9:9",
+		main + ":14:1",
+	}, positions)
+}
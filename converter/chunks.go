@@ -0,0 +1,285 @@
+package converter
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/refaktor/ryegen/v2/converter/walktypes"
+)
+
+// A Chunk is an independent part of the generated converter code.
+// Chunks of different [ConverterSet]s (e.g. for different build
+// targets) with the same key and code can be shared between them.
+type Chunk struct {
+	// Identifies the part of the code, e.g. the converter
+	// of a type in a direction.
+	Key string
+	// Top-level declarations, or statements if Init is set.
+	Code []byte
+	// Code consists of statements that have to run in an
+	// init func.
+	Init bool
+	// Packages referenced by Code.
+	Imports []*types.Package
+	// Path of the package the code mainly belongs to, e.g. the
+	// package of the converted type, or "" if there is none.
+	Pkg string
+}
+
+// Chunks is like [ConverterSet.Code], but returns the code split
+// into chunks, sorted by [SortChunks]. Use [ChunksCode] to
+// assemble chunks into a file.
+func (cs *ConverterSet) Chunks() ([]Chunk, *Graph, error) {
+	parts := cs.genParts()
+	res := append(cs.partsChunks(parts), NativeKindNamesChunk(cs.kindNames))
+	SortChunks(res)
+	return res, newGraph(parts.graph, cs.tset, parts.nativeKinds), newConverterError(parts.graph)
+}
+
+// ChunksFor returns the chunks of [ConverterSet.Chunks] that
+// the converters of nodes (and the converters they depend on)
+// consist of, except for the native kind names (see
+// [NativeKindNamesChunk]). It also returns the default names of
+// the native kinds the converters create (see
+// [ConverterSet.SetNativeKindNames]). graph must be the one
+// returned by the last call to Chunks. Nodes not contained in
+// graph are ignored.
+//
+// The chunks of several calls can be merged by removing
+// duplicates (see [ChunkID]) and adding the native kind names.
+func (cs *ConverterSet) ChunksFor(graph *Graph, nodes []GraphNode) (chunks []Chunk, defaultNativeKinds []string) {
+	closure := map[convKey]convNode{}
+	var addNext []convKey
+	for _, n := range nodes {
+		addNext = append(addNext, convKey{typString: graph.typeSet.TypeString(n.Type), dir: n.Dir})
+	}
+	for len(addNext) > 0 {
+		key := addNext[len(addNext)-1]
+		addNext = addNext[:len(addNext)-1]
+		if _, ok := closure[key]; ok {
+			continue
+		}
+		node, ok := graph.nodes[key]
+		if !ok {
+			continue
+		}
+		closure[key] = node
+		for _, dep := range node.deps {
+			addNext = append(addNext, dep.key)
+		}
+	}
+
+	for key := range closure {
+		defaultNativeKinds = append(defaultNativeKinds, cs.nativeKinds[key]...)
+	}
+	chunks = cs.partsChunks(cs.makeParts(graph.convGraph, closure))
+	SortChunks(chunks)
+	return chunks, sortedUniq(defaultNativeKinds, strings.Compare)
+}
+
+// partsChunks returns the chunks of parts, except for
+// the native kind names.
+func (cs *ConverterSet) partsChunks(parts codeParts) []Chunk {
+	var res []Chunk
+
+	res = append(res, Chunk{
+		Key:  "prelude",
+		Code: []byte(strings.TrimPrefix(preludeBody, "\n") + "\nvar typeLookup = map[string]map[string]string{}\n"),
+	})
+
+	if parts.reflect {
+		res = append(res, Chunk{
+			Key:  "reflectPrelude",
+			Code: []byte(strings.TrimPrefix(reflectPreludeBody, "\n")),
+		})
+	}
+
+	for _, alias := range parts.aliases {
+		var imports []*types.Package
+		var addImports func(t types.Type)
+		addImports = func(t types.Type) {
+			switch t := t.(type) {
+			case *types.Named:
+				if pkg := t.Obj().Pkg(); pkg != nil && pkg.Path() != cs.basePkg {
+					imports = append(imports, pkg)
+				}
+			case *types.Basic:
+				if t.Kind() == types.UnsafePointer {
+					imports = append(imports, types.Unsafe)
+				}
+			}
+			walktypes.Walk(t, addImports)
+		}
+		addImports(alias.Type)
+		res = append(res, Chunk{
+			Key:     "alias:" + alias.Name,
+			Code:    fmt.Appendf(nil, "type %v = %v\n", alias.Name, types.TypeString(alias.Type, cs.tset.Qualifier())),
+			Imports: sortedUniq(imports, cmpPkgs),
+		})
+	}
+
+	for _, nt := range parts.namedTypes {
+		pkg, name, entry := cs.typeLookupEntry(nt)
+		res = append(res, Chunk{
+			Key:  "typeLookup:" + pkg + "." + name,
+			Code: fmt.Appendf(nil, "addTypeLookup(%q, %q, %q)\n", pkg, name, entry),
+			Init: true,
+			Pkg:  pkg,
+		})
+	}
+
+	for key, node := range parts.nodes {
+		res = append(res, Chunk{
+			Key:     key.String(),
+			Code:    node.code,
+			Imports: node.imports,
+			Pkg:     cs.typePkg(node.typ),
+		})
+	}
+
+	return res
+}
+
+// NativeKindNamesChunk returns the chunk declaring the
+// native kind names (see [ConverterSet.SetNativeKindNames]).
+func NativeKindNamesChunk(names map[string]string) Chunk {
+	return Chunk{
+		Key:  "nativeKindNames",
+		Code: []byte(nativeKindNamesCode(names)),
+	}
+}
+
+// SortChunks sorts chunks in a stable order: the preludes and
+// native kind names first, followed by the type aliases and the
+// type lookup entries, and finally the converters.
+func SortChunks(chunks []Chunk) {
+	type sortKey struct {
+		rank int
+		pkg  string // of type lookup entries
+		conv convKey
+	}
+	key := func(c Chunk) sortKey {
+		switch {
+		case c.Key == "prelude":
+			return sortKey{rank: 0}
+		case c.Key == "nativeKindNames":
+			return sortKey{rank: 1}
+		case c.Key == "reflectPrelude":
+			return sortKey{rank: 2}
+		case strings.HasPrefix(c.Key, "alias:"):
+			return sortKey{rank: 3}
+		case strings.HasPrefix(c.Key, "typeLookup:"):
+			return sortKey{rank: 4, pkg: c.Pkg}
+		}
+		if conv, ok := parseConvKey(c.Key); ok {
+			return sortKey{rank: 5, conv: conv}
+		}
+		return sortKey{rank: 6}
+	}
+	slices.SortStableFunc(chunks, func(a, b Chunk) int {
+		ka, kb := key(a), key(b)
+		return cmp.Or(
+			cmp.Compare(ka.rank, kb.rank),
+			cmp.Compare(ka.pkg, kb.pkg),
+			ka.conv.cmp(kb.conv),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+}
+
+// ChunksCode assembles chunks into the code of a Go file
+// (without the package clause). All chunks required by chunks
+// must be part of the same Go package, e.g. by being in other
+// files generated by ChunksCode. The qualifier has to give the
+// same import names as the one of the [ConverterSet] the chunks
+// were created from.
+func ChunksCode(chunks []Chunk, qualifier types.Qualifier) []byte {
+	var b bytes.Buffer
+
+	// Imports
+	{
+		var imports []*types.Package
+		for _, c := range chunks {
+			imports = append(imports, c.Imports...)
+		}
+		imports = sortedUniq(imports, cmpPkgs)
+		if len(imports) > 0 {
+			b.WriteString("import (\n")
+			for _, imp := range imports {
+				b.WriteString("\t" + qualifier(imp) + " " + `"` + imp.Path() + `"` + "\n")
+			}
+			b.WriteString(")\n")
+		}
+		b.WriteString(preludeImports)
+		b.WriteString("\n")
+		// The prelude may be in another file.
+		b.WriteString("// Force-use the prelude imports.\n")
+		for _, s := range []string{"_errors.ErrUnsupported", "_fmt.Sprint", "_reflect.TypeOf", "_strings.Repeat", "_env.NewVoid", "_evaldo.BuiltinNames"} {
+			b.WriteString("var _ = " + s + "\n")
+		}
+		b.WriteString("var _ _sync.Mutex\n\n")
+	}
+
+	// Init statements
+	{
+		var stmts []Chunk
+		for _, c := range chunks {
+			if c.Init {
+				stmts = append(stmts, c)
+			}
+		}
+		if len(stmts) > 0 {
+			b.WriteString("func init() {\n")
+			for _, c := range stmts {
+				for line := range strings.Lines(string(c.Code)) {
+					b.WriteString("\t" + line)
+				}
+			}
+			b.WriteString("}\n\n")
+		}
+	}
+
+	// Declarations
+	first := true
+	for _, c := range chunks {
+		if c.Init {
+			continue
+		}
+		if !first {
+			b.WriteString("\n\n")
+		}
+		b.Write(c.Code)
+		first = false
+	}
+
+	return b.Bytes()
+}
+
+// ChunkID returns a string that is equal for two chunks
+// if and only if they can be used interchangeably.
+func ChunkID(c Chunk) string {
+	return c.Key + "\x00" + string(c.Code)
+}
+
+// typePkg returns the path of the package of the first
+// named type in typ, or "" if there is none.
+func (cs *ConverterSet) typePkg(typ types.Type) string {
+	var res string
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		if res != "" {
+			return
+		}
+		if t, ok := t.(*types.Named); ok && t.Obj().Pkg() != nil {
+			res = t.Obj().Pkg().Path()
+			return
+		}
+		walktypes.Walk(t, visit)
+	}
+	visit(typ)
+	return res
+}
@@ -8,8 +8,10 @@ import (
 	"go/types"
 	"hash/fnv"
 	"maps"
+	"regexp"
 	"slices"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/refaktor/ryegen/v2/converter/typeset"
@@ -186,7 +188,12 @@ type convSpec struct {
 	dir Direction
 }
 
+// ConverterSet generates converters between Go and Rye values.
+// Its methods are safe for concurrent use. The converters are
+// calculated in parallel, but the generated code doesn't depend
+// on the scheduling.
 type ConverterSet struct {
+	mu          sync.Mutex           // guards seedConvs
 	seedConvs   map[convKey]convInfo // see [makeConvGraph]
 	tmplToRye   *template.Template
 	tmplFromRye *template.Template
@@ -195,14 +202,13 @@ type ConverterSet struct {
 	tset  *typeset.TypeSet
 	onces map[string]struct{} // see "once" in [templateFuncMap]
 
-	// The following variables are part of template dependency
-	// injection (which is kind of a hack). This allows arbitrary
-	// values to be inserted into and extracted from template
-	// funcs.
-	// They have to be re-used constantly, so best don't touch them.
-	newImports []*types.Package
-	newDeps    []convSpec
-	canConvert func(convInfo) bool
+	// Default names of the native kinds created by each
+	// calculated converter
+	nativeKinds map[convKey][]string
+	// See [ConverterSet.SetNativeKindNames]
+	kindNames map[string]string
+	// See [ConverterSet.SetReflectTypes]
+	reflectTypes []*regexp.Regexp
 }
 
 // NewConverterSet creates a new [ConverterSet].
@@ -210,26 +216,59 @@ type ConverterSet struct {
 // in (usually "main").
 func NewConverterSet(tset *typeset.TypeSet, basePkg string) *ConverterSet {
 	cs := &ConverterSet{
-		seedConvs: map[convKey]convInfo{},
-		basePkg:   basePkg,
-		tset:      tset,
-		onces:     map[string]struct{}{},
+		seedConvs:   map[convKey]convInfo{},
+		basePkg:     basePkg,
+		tset:        tset,
+		onces:       map[string]struct{}{},
+		nativeKinds: map[convKey][]string{},
 	}
 
-	// Set up template functions that use template dependency
-	// injection
-	funcs := maps.Clone(templateFuncMap)
+	// The dynamically generated funcs are set
+	// for each tmplExec.
+	cs.tmplToRye = template.Must(template.New("to_rye.go.tmpl").Funcs(templateFuncMap).
+		ParseFS(templates, "templates/common.go.tmpl", "templates/to_rye.go.tmpl"))
+	cs.tmplFromRye = template.Must(template.New("from_rye.go.tmpl").Funcs(templateFuncMap).
+		ParseFS(templates, "templates/common.go.tmpl", "templates/from_rye.go.tmpl"))
+
+	return cs
+}
+
+// tmplExec executes the converter templates. This is part of
+// template dependency injection (which is kind of a hack): the
+// template funcs insert values into and extract them from the
+// tmplExec, so each goroutine executing templates needs its own
+// tmplExec (see [ConverterSet.newTmplExec]).
+type tmplExec struct {
+	toRye, fromRye *template.Template
+
+	// Collected by the template funcs during an execution
+	newImports     []*types.Package
+	newDeps        []convSpec
+	newNativeKinds []string
+
+	// See [calcNodeFunc]
+	canConvert func(convInfo) bool
+	// Returns whether the string is passed to once
+	// for the first time (see "once" in [templateFuncMap])
+	once func(s string) bool
+}
+
+func (cs *ConverterSet) newTmplExec() *tmplExec {
+	ex := &tmplExec{}
+
+	funcs := template.FuncMap{}
 	funcs["conv"] = func(typ types.Type, dir Direction) string {
 		typ = cs.tset.Normalized(typ)
-		cs.newDeps = append(cs.newDeps, convSpec{typ, dir})
+		ex.newDeps = append(ex.newDeps, convSpec{typ, dir})
 		return cs.convName(typ, dir)
 	}
 	funcs["canConv"] = func(typ types.Type, dir Direction) bool {
 		key := convKey{typString: cs.tset.TypeString(typ), dir: dir}
 		info := convInfo{key: key, typ: typ}
-		return cs.canConvert(info)
+		return ex.canConvert(info)
 	}
-	funcs["typStr"] = func(t types.Type) (string, error) {
+	var typStr func(t types.Type) (string, error)
+	typStr = func(t types.Type) (string, error) {
 		var collectImports func(t types.Type)
 		collectImports = func(t types.Type) {
 			switch t := t.(type) {
@@ -237,13 +276,13 @@ func NewConverterSet(tset *typeset.TypeSet, basePkg string) *ConverterSet {
 				if t.Obj().Exported() {
 					if pkg := t.Obj().Pkg(); pkg != nil {
 						if pkg.Path() != cs.basePkg {
-							cs.newImports = append(cs.newImports, pkg)
+							ex.newImports = append(ex.newImports, pkg)
 						}
 					}
 				}
 			case *types.Basic:
 				if t.Kind() == types.UnsafePointer {
-					cs.newImports = append(cs.newImports, types.Unsafe)
+					ex.newImports = append(ex.newImports, types.Unsafe)
 				}
 			}
 			walktypes.Walk(t, collectImports)
@@ -251,24 +290,92 @@ func NewConverterSet(tset *typeset.TypeSet, basePkg string) *ConverterSet {
 		collectImports(t)
 		return cs.tset.TypeString(t), nil
 	}
+	funcs["typStr"] = typStr
+	funcs["nativeKind"] = func(t types.Type) (string, error) {
+		s, err := typStr(t)
+		if err != nil {
+			return "", err
+		}
+		kind := "go(" + s + ")"
+		ex.newNativeKinds = append(ex.newNativeKinds, kind)
+		return cs.nativeKindName(kind), nil
+	}
 	funcs["typHash"] = func(typ types.Type) string {
 		return typeHash(cs.tset.TypeString(typ))
 	}
 	funcs["once"] = func(s string) bool {
-		if _, seen := cs.onces[s]; seen {
-			return false
-		} else {
-			cs.onces[s] = struct{}{}
+		return ex.once(s)
+	}
+
+	ex.toRye = template.Must(cs.tmplToRye.Clone()).Funcs(funcs)
+	ex.fromRye = template.Must(cs.tmplFromRye.Clone()).Funcs(funcs)
+	return ex
+}
+
+// SetNativeKindNames renames the kinds of natives created by
+// converters, e.g. "go(*net_http.Client)" -> "go(*net-http.client)".
+// Kinds not in names keep their default name. Must be called
+// before [ConverterSet.Code].
+func (cs *ConverterSet) SetNativeKindNames(names map[string]string) {
+	cs.kindNames = names
+}
+
+// nativeKindName returns the name of the native kind with
+// the default name kind (see [ConverterSet.SetNativeKindNames]).
+func (cs *ConverterSet) nativeKindName(kind string) string {
+	if name, ok := cs.kindNames[kind]; ok {
+		return name
+	}
+	return kind
+}
+
+// SetReflectTypes makes the converters of all types fully
+// matched by any of res convert using reflection at runtime
+// (see reflectToRye and reflectFromRye) instead of generated
+// code. Types are matched against their string in the
+// generated code, e.g. "*net_http.Request". Such converters
+// don't depend on any other converters, which keeps the
+// generated code small, but they are slower and can't
+// translate channels. Must be called before [ConverterSet.Code].
+func (cs *ConverterSet) SetReflectTypes(res []*regexp.Regexp) {
+	cs.reflectTypes = res
+}
+
+// isReflectType returns whether the converters of the type
+// with the given type string use reflection (see
+// [ConverterSet.SetReflectTypes]).
+func (cs *ConverterSet) isReflectType(typString string) bool {
+	for _, re := range cs.reflectTypes {
+		if loc := re.FindStringIndex(typString); loc != nil && loc[0] == 0 && loc[1] == len(typString) {
 			return true
 		}
 	}
+	return false
+}
 
-	cs.tmplToRye = template.Must(template.New("to_rye.go.tmpl").Funcs(funcs).
-		ParseFS(templates, "templates/common.go.tmpl", "templates/to_rye.go.tmpl"))
-	cs.tmplFromRye = template.Must(template.New("from_rye.go.tmpl").Funcs(funcs).
-		ParseFS(templates, "templates/common.go.tmpl", "templates/from_rye.go.tmpl"))
-
-	return cs
+// checkReflectable returns an error if a converter using
+// reflection can't be generated for typ. Such converters spell
+// out their type, which isn't possible for interfaces with
+// unexported methods of other packages. Generated converters
+// don't need this check, since there are no templates for
+// unnamed interfaces.
+func (cs *ConverterSet) checkReflectable(typ types.Type) error {
+	var check func(t types.Type) error
+	check = func(t types.Type) error {
+		switch t := t.(type) {
+		case *types.Named, *types.Alias:
+			// Spelled by name
+			return nil
+		case *types.Interface:
+			for m := range t.ExplicitMethods() {
+				if !m.Exported() && m.Pkg() != nil && m.Pkg().Path() != cs.basePkg {
+					return ErrUnexported
+				}
+			}
+		}
+		return walktypes.WalkErr(t, check)
+	}
+	return check(typ)
 }
 
 func (cs *ConverterSet) typeUniqueName(typ types.Type) string {
@@ -385,27 +492,32 @@ func (cs *ConverterSet) templateName(typ types.Type) (string, error) {
 	return "", fmt.Errorf("no known converter template for type %v", typ)
 }
 
-// executeTemplate executes the converter template tmpl on data and
-// returns the generated code, and the collected converter dependencies
-// and import dependencies.
-func (cs *ConverterSet) executeTemplate(tmpl *template.Template, data types.Type) (code []byte, deps []convSpec, imports []*types.Package, err error) {
+// execute executes the converter template tmpl on data and
+// returns the generated code, the collected converter dependencies,
+// import dependencies and native kind names.
+func (ex *tmplExec) execute(tmpl *template.Template, data types.Type, qualifier types.Qualifier) (code []byte, deps []convSpec, imports []*types.Package, nativeKinds []string, err error) {
 	defer func() {
-		cs.newDeps = cs.newDeps[:0]
-		cs.newImports = cs.newImports[:0]
+		ex.newDeps = ex.newDeps[:0]
+		ex.newImports = ex.newImports[:0]
+		ex.newNativeKinds = ex.newNativeKinds[:0]
 	}()
 
 	var b bytes.Buffer
 	if err := tmpl.Execute(&b, data); err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	// New first-order converter dependencies and
-	// imports have been collected in newDeps/newImports
-	// by the template execution.
-	deps = slices.Clone(cs.newDeps)
-	imports = slices.Clone(cs.newImports)
-
-	return b.Bytes(), deps, imports, nil
+	// New first-order converter dependencies,
+	// imports and native kinds have been collected
+	// in newDeps/newImports/newNativeKinds by the
+	// template execution.
+	deps = slices.Clone(ex.newDeps)
+	// Some of the imports may only have been collected for
+	// type names in string literals.
+	imports = usedImports(b.Bytes(), ex.newImports, qualifier)
+	nativeKinds = slices.Clone(ex.newNativeKinds)
+
+	return b.Bytes(), deps, imports, nativeKinds, nil
 }
 
 // Add adds a converter to the ConverterSet, meaning it will end up
@@ -417,6 +529,8 @@ func (cs *ConverterSet) Add(typ types.Type, dir Direction, debugName string) (co
 	typ = cs.tset.Normalized(typ)
 	key := convKey{typString: cs.tset.TypeString(typ), dir: dir}
 	info := convInfo{key: key, typ: typ}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	if prevInfo, ok := cs.seedConvs[key]; ok {
 		info.debugNames = prevInfo.debugNames
 	}
@@ -441,73 +555,235 @@ func (cs *ConverterSet) Code() ([]byte, *Graph, error) {
 	return cs.genCode(true)
 }
 
-func (cs *ConverterSet) genGraph() convGraph {
-	return makeConvGraph(
-		slices.SortedFunc(maps.Values(cs.seedConvs), func(a, b convInfo) int { return a.key.cmp(b.key) }),
-		func(ci convInfo, canConvert func(convInfo) bool) (_code []byte, _deps []convInfo, _imports []*types.Package, _err error) {
-			cs.canConvert = canConvert
-			defer func() {
-				cs.canConvert = nil
-			}()
-
-			typ := ci.typ
-			if cs.tset.ContainsAlias(typ) {
-				// We don't want a struct converter to deal with the aliased type
-				typ = typ.Underlying()
-			}
+// nodeResult is the result of calculating a converter
+// node (see [calcNodeFunc]).
+type nodeResult struct {
+	code        []byte
+	deps        []convInfo
+	imports     []*types.Package
+	nativeKinds []string
+	err         error
+}
 
-			if err := checkConvertible(typ); err != nil {
-				return nil, nil, nil, err
-			}
+// calcNode calculates the converter node of ci
+// by executing its template with ex.
+func (cs *ConverterSet) calcNode(ex *tmplExec, ci convInfo) nodeResult {
+	typ := ci.typ
+	if cs.tset.ContainsAlias(typ) {
+		// We don't want a struct converter to deal with the aliased type
+		typ = typ.Underlying()
+	}
+
+	if err := checkConvertible(typ); err != nil {
+		return nodeResult{err: err}
+	}
+
+	var tmpl *template.Template
+	switch ci.key.dir {
+	case ToRye:
+		tmpl = ex.toRye
+	case FromRye:
+		tmpl = ex.fromRye
+	default:
+		panic("invalid conversion direction")
+	}
+	tmplName := "reflect"
+	if cs.isReflectType(ci.key.typString) {
+		if err := cs.checkReflectable(typ); err != nil {
+			return nodeResult{err: err}
+		}
+	} else {
+		var err error
+		tmplName, err = cs.templateName(typ)
+		if err != nil {
+			return nodeResult{err: err}
+		}
+	}
+	tmpl = tmpl.Lookup(tmplName)
+	if tmpl == nil {
+		return nodeResult{err: fmt.Errorf("no template to convert %v %v", tmplName, ci.key.dir)}
+	}
+	code, deps, imports, nativeKinds, err := ex.execute(tmpl, typ, cs.tset.Qualifier())
+	if err != nil {
+		return nodeResult{err: fmt.Errorf("execute converter template for %v %v: %w", tmplName, ci.key.dir, err)}
+	}
+	depInfos := make([]convInfo, len(deps))
+	for i, dep := range deps {
+		depInfos[i] = convInfo{
+			key: convKey{
+				typString: cs.tset.TypeString(dep.typ),
+				dir:       dep.dir,
+			},
+			typ: dep.typ,
+		}
+	}
+	return nodeResult{
+		code:        code,
+		deps:        depInfos,
+		imports:     imports,
+		nativeKinds: nativeKinds,
+	}
+}
 
-			var tmpl *template.Template
-			switch ci.key.dir {
-			case ToRye:
-				tmpl = cs.tmplToRye
-			case FromRye:
-				tmpl = cs.tmplFromRye
-			default:
-				panic("invalid conversion direction")
+func (cs *ConverterSet) genGraph() convGraph {
+	cs.mu.Lock()
+	seeds := slices.SortedFunc(maps.Values(cs.seedConvs), func(a, b convInfo) int { return a.key.cmp(b.key) })
+	cs.mu.Unlock()
+
+	// Template executors for calculating nodes in order. A
+	// template may need another node to be calculated first
+	// (see canConvert), so they can't be shared.
+	var execs []*tmplExec
+	once := func(s string) bool {
+		if _, seen := cs.onces[s]; seen {
+			return false
+		}
+		cs.onces[s] = struct{}{}
+		return true
+	}
+
+	// Results calculated ahead of time by prefetch, along
+	// with the type they were calculated for and the once
+	// strings they claimed
+	type prefetched struct {
+		nodeResult
+		typ   types.Type
+		onces []string
+	}
+	results := map[convKey]prefetched{}
+
+	// prefetch calculates the nodes of batch in parallel. A
+	// result is only used if it's the same as if the node was
+	// calculated when it's needed: if the node's template
+	// depends on whether a node can be converted, the answer
+	// has to be known already, and the strings the template
+	// passed to once must not have been claimed in between.
+	prefetch := func(batch []convInfo, incomplete func(convKey) bool) {
+		batchResults := make([]prefetched, len(batch))
+		valid := make([]bool, len(batch))
+		parallelFor(len(batch), func() func(i int) {
+			wex := cs.newTmplExec()
+			return func(i int) {
+				ci := batch[i]
+				var onces []string
+				needsOrder := false
+				wex.canConvert = func(dep convInfo) bool {
+					if dep.key == ci.key {
+						return true
+					}
+					if incomplete(dep.key) {
+						// Incomplete nodes stay incomplete
+						return false
+					}
+					needsOrder = true
+					return true
+				}
+				wex.once = func(s string) bool {
+					// cs.onces isn't modified during prefetch
+					if _, seen := cs.onces[s]; seen {
+						return false
+					}
+					onces = append(onces, s)
+					return true
+				}
+				batchResults[i] = prefetched{cs.calcNode(wex, ci), ci.typ, onces}
+				valid[i] = !needsOrder
 			}
-			tmplName, err := cs.templateName(typ)
-			if err != nil {
-				return nil, nil, nil, err
+		})
+		for i, ci := range batch {
+			if valid[i] {
+				results[ci.key] = batchResults[i]
 			}
-			tmpl = tmpl.Lookup(tmplName)
-			if tmpl == nil {
-				return nil, nil, nil, fmt.Errorf("no template to convert %v %v", tmplName, ci.key.dir)
+		}
+	}
+
+	calcNode := func(ci convInfo, canConvert func(convInfo) bool) (_code []byte, _deps []convInfo, _imports []*types.Package, _err error) {
+		res, ok := results[ci.key]
+		if ok {
+			delete(results, ci.key)
+			// Different types can have the same key, e.g.
+			// "interface{}" and "any".
+			if res.typ != ci.typ || slices.ContainsFunc(res.onces, func(s string) bool {
+				_, seen := cs.onces[s]
+				return seen
+			}) {
+				ok = false
 			}
-			code, deps, importPaths, err := cs.executeTemplate(tmpl, typ)
-			if err != nil {
-				return nil, nil, nil, fmt.Errorf("execute converter template for %v %v: %w", tmplName, ci.key.dir, err)
+		}
+		if ok {
+			for _, s := range res.onces {
+				cs.onces[s] = struct{}{}
 			}
-			depInfos := make([]convInfo, len(deps))
-			for i, dep := range deps {
-				depInfos[i] = convInfo{
-					key: convKey{
-						typString: cs.tset.TypeString(dep.typ),
-						dir:       dep.dir,
-					},
-					typ: dep.typ,
-				}
+		} else {
+			var ex *tmplExec
+			if len(execs) > 0 {
+				ex, execs = execs[len(execs)-1], execs[:len(execs)-1]
+			} else {
+				ex = cs.newTmplExec()
+				ex.once = once
 			}
-			return code, depInfos, importPaths, nil
-		},
-	)
+			ex.canConvert = canConvert
+			res.nodeResult = cs.calcNode(ex, ci)
+			execs = append(execs, ex)
+		}
+		if res.err != nil {
+			return nil, nil, nil, res.err
+		}
+		cs.nativeKinds[ci.key] = res.nativeKinds
+		return res.code, res.deps, res.imports, nil
+	}
+
+	return makeConvGraph(seeds, calcNode, prefetch)
 }
 
-func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
+// codeParts holds the parts the generated code
+// is made of.
+type codeParts struct {
+	graph       convGraph
+	nodes       map[convKey]convNode // the nodes the parts are made of
+	namedTypes  []*types.TypeName    // sorted
+	imports     []*types.Package     // sorted
+	nativeKinds []string             // sorted
+	convCode    map[convKey][]byte
+	aliases     []typeset.Alias // only used ones; sorted by name
+	// Whether any converters use reflection
+	// (see [ConverterSet.SetReflectTypes])
+	reflect bool
+}
+
+func (cs *ConverterSet) genParts() codeParts {
 	graph := cs.genGraph()
+	return cs.makeParts(graph, graph.nodes)
+}
 
+// makeParts returns the parts of the code of the given
+// nodes of graph.
+func (cs *ConverterSet) makeParts(graph convGraph, nodes map[convKey]convNode) codeParts {
 	var namedTypes []*types.TypeName
 	var imports []*types.Package
+	var nativeKinds []string
+	var reflect bool
+	usedAliases := map[string]struct{}{} // by type string
 	convCode := map[convKey][]byte{}
 	{
-		for key, node := range graph.nodes {
+		for key, node := range nodes {
+			for _, kind := range cs.nativeKinds[key] {
+				nativeKinds = append(nativeKinds, cs.nativeKindName(kind))
+			}
+			reflect = reflect || cs.isReflectType(key.typString)
+
 			var addNamedTypes func(typ types.Type)
 			addNamedTypes = func(typ types.Type) {
-				if typ, ok := typ.(*types.Named); ok {
+				switch typ := typ.(type) {
+				case *types.Named:
 					namedTypes = append(namedTypes, typ.Obj())
+				case *types.Alias:
+					// E.g. in the signature of a converter
+					// using reflection, which doesn't
+					// depend on the struct's converter
+					if cs.tset.ContainsAlias(typ) {
+						usedAliases[cs.tset.TypeString(typ)] = struct{}{}
+					}
 				}
 				walktypes.Walk(typ, addNamedTypes)
 			}
@@ -522,8 +798,76 @@ func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
 				cmp.Compare(a.Name(), b.Name()))
 		})
 		imports = sortedUniq(imports, cmpPkgs)
+		nativeKinds = sortedUniq(nativeKinds, strings.Compare)
 	}
 
+	var aliases []typeset.Alias
+	for alias := range cs.tset.Aliases() {
+		typStr := cs.tset.TypeString(alias.Type)
+		_, ok := nodes[convKey{typString: typStr, dir: ToRye}]
+		if !ok {
+			_, ok = nodes[convKey{typString: typStr, dir: FromRye}]
+		}
+		if !ok {
+			_, ok = usedAliases[typStr]
+		}
+		if ok {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return codeParts{
+		graph:       graph,
+		nodes:       nodes,
+		namedTypes:  namedTypes,
+		imports:     imports,
+		nativeKinds: nativeKinds,
+		convCode:    convCode,
+		aliases:     aliases,
+		reflect:     reflect,
+	}
+}
+
+// typeLookupEntry returns the package path, name and
+// typeLookup entry of a named type (see autoToNative).
+func (cs *ConverterSet) typeLookupEntry(nt *types.TypeName) (pkg, name, entry string) {
+	if nt.Pkg() != nil {
+		pkg = nt.Pkg().Path()
+	}
+	if pkg != "" && pkg != cs.basePkg {
+		entry += cs.tset.Qualifier()(nt.Pkg()) + "."
+	}
+	entry += nt.Name()
+	return pkg, nt.Name(), entry
+}
+
+// nativeKindNamesCode returns the declaration of
+// nativeKindNames (see [ConverterSet.SetNativeKindNames]).
+func (cs *ConverterSet) nativeKindNamesCode() string {
+	return nativeKindNamesCode(cs.kindNames)
+}
+
+func nativeKindNamesCode(kindNames map[string]string) string {
+	var b strings.Builder
+	b.WriteString("var nativeKindNames = map[string]string{")
+	for _, kind := range slices.Sorted(maps.Keys(kindNames)) {
+		fmt.Fprintf(&b, "\n\t%q: %q,", kind, kindNames[kind])
+	}
+	if len(kindNames) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
+	parts := cs.genParts()
+	graph := parts.graph
+	imports := parts.imports
+	namedTypes := parts.namedTypes
+	nativeKinds := parts.nativeKinds
+	convCode := parts.convCode
+
 	var b bytes.Buffer
 
 	// Imports
@@ -539,26 +883,18 @@ func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
 	if withPrelude {
 		b.WriteString(preludeCode)
 		b.WriteString("\n")
+		if parts.reflect {
+			b.WriteString(strings.TrimPrefix(reflectPreludeBody, "\n"))
+			b.WriteString("\n")
+		}
 	}
 
 	// Struct alias declarations
-	{
-		found := false
-		for alias := range cs.tset.Aliases() {
-			typStr := cs.tset.TypeString(alias.Type)
-			_, ok := graph.nodes[convKey{typString: typStr, dir: ToRye}]
-			if !ok {
-				_, ok = graph.nodes[convKey{typString: typStr, dir: FromRye}]
-			}
-			if !ok {
-				continue
-			}
+	if len(parts.aliases) > 0 {
+		for _, alias := range parts.aliases {
 			fmt.Fprintf(&b, "type %v = %v\n", alias.Name, types.TypeString(alias.Type, cs.tset.Qualifier()))
-			found = true
-		}
-		if found {
-			b.WriteString("\n")
 		}
+		b.WriteString("\n")
 	}
 
 	// Type names
@@ -567,26 +903,24 @@ func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
 		b.WriteString("func init() {\n")
 		seenPkgs := map[string]struct{}{}
 		for _, nt := range namedTypes {
-			pkg := ""
-			if nt.Pkg() != nil {
-				pkg = nt.Pkg().Path()
-			}
+			pkg, name, ntStr := cs.typeLookupEntry(nt)
 			if _, ok := seenPkgs[pkg]; !ok {
 				b.WriteString("\t" + `typeLookup["` + pkg + `"] = map[string]string{}` + "\n")
 				seenPkgs[pkg] = struct{}{}
 			}
-
-			var ntStr string
-			if pkg != "" && pkg != cs.basePkg {
-				ntStr += cs.tset.Qualifier()(nt.Pkg()) + "."
-			}
-			ntStr += nt.Name()
-			b.WriteString("\t" + `typeLookup["` + pkg + `"]["` + nt.Name() + `"] = "` + ntStr + `"` + "\n")
+			b.WriteString("\t" + `typeLookup["` + pkg + `"]["` + name + `"] = "` + ntStr + `"` + "\n")
 		}
 		b.WriteString("}\n\n")
 
 	}
 
+	// Native kind names used by the prelude's autoToNative
+	// (see [ConverterSet.SetNativeKindNames])
+	if withPrelude {
+		b.WriteString(cs.nativeKindNamesCode())
+		b.WriteString("\n")
+	}
+
 	// Converter code
 	{
 		for i, key := range slices.SortedFunc(maps.Keys(convCode), convKey.cmp) {
@@ -598,5 +932,5 @@ func (cs *ConverterSet) genCode(withPrelude bool) ([]byte, *Graph, error) {
 		}
 	}
 
-	return b.Bytes(), newGraph(graph, cs.tset), newConverterError(graph)
+	return b.Bytes(), newGraph(graph, cs.tset, nativeKinds), newConverterError(graph)
 }
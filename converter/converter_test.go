@@ -7,6 +7,7 @@ import (
 	"go/types"
 	"os"
 	"path"
+	"regexp"
 	"testing"
 
 	"github.com/refaktor/ryegen/v2/converter/typeset"
@@ -53,6 +54,11 @@ func TestConverter(t *testing.T) {
 	testConverter(t, "from_rye/any.go", "any", FromRye)
 	testConverter(t, "from_rye/chan_int.go", "chan int", FromRye)
 	testConverter(t, "from_rye/chan_int_s.go", "<-chan int", FromRye)
+
+	// Using reflection
+	testReflectConverter(t, "to_rye/reflect_struct.go", "struct{ A []int; B map[string]int }", ToRye)
+	testReflectConverter(t, "to_rye/reflect_func.go", "func(a int) (string, error)", ToRye)
+	testReflectConverter(t, "from_rye/reflect_struct.go", "struct{ A []int; B map[string]int }", FromRye)
 }
 
 // If filename doesn't exist, the resulting converter will be written to the file
@@ -61,11 +67,20 @@ func TestConverter(t *testing.T) {
 func testConverter(t *testing.T, filename, typeExpr string, dir Direction) {
 	t.Helper()
 	t.Run(filename, func(t *testing.T) {
-		doTestConverter(t, filename, typeExpr, dir)
+		doTestConverter(t, filename, typeExpr, dir, false)
+	})
+}
+
+// Like testConverter, but all types are converted using
+// reflection (see [ConverterSet.SetReflectTypes]).
+func testReflectConverter(t *testing.T, filename, typeExpr string, dir Direction) {
+	t.Helper()
+	t.Run(filename, func(t *testing.T) {
+		doTestConverter(t, filename, typeExpr, dir, true)
 	})
 }
 
-func doTestConverter(t *testing.T, filename, typeExpr string, dir Direction) {
+func doTestConverter(t *testing.T, filename, typeExpr string, dir Direction, reflect bool) {
 	require := require.New(t)
 	filePath := path.Join("testdata", filename)
 	require.NoError(os.MkdirAll(path.Dir(filePath), os.ModePerm))
@@ -79,6 +94,9 @@ func doTestConverter(t *testing.T, filename, typeExpr string, dir Direction) {
 	err = types.CheckExpr(token.NewFileSet(), nil, token.NoPos, typExpr, &info)
 	require.NoError(err)
 	cs := NewConverterSet(typeset.New(nil), "main")
+	if reflect {
+		cs.SetReflectTypes([]*regexp.Regexp{regexp.MustCompile(".*")})
+	}
 	_ = cs.Add(info.TypeOf(typExpr), dir, "")
 	got, _, err := cs.genCode(false)
 	require.NoError(err)
@@ -91,3 +109,91 @@ func doTestConverter(t *testing.T, filename, typeExpr string, dir Direction) {
 		require.NoError(err)
 	}
 }
+
+func TestUsedImports(t *testing.T) {
+	ioPkg := types.NewPackage("io", "io")
+	x509 := types.NewPackage("crypto/x509", "x509")
+	qualifier := func(p *types.Package) string {
+		return map[string]string{"io": "io", "crypto/x509": "crypto_x509"}[p.Path()]
+	}
+	code := []byte(`func f(r io.Reader) error { return errors.New("expected crypto_x509.Certificate") }`)
+	require.Equal(t, []*types.Package{ioPkg}, usedImports(code, []*types.Package{ioPkg, x509}, qualifier))
+}
+
+func TestReflectUnexportedMethod(t *testing.T) {
+	require := require.New(t)
+
+	// func() interface{ m() }, with m unexported in another package
+	pkg := types.NewPackage("example.com/a", "a")
+	m := types.NewFunc(token.NoPos, pkg, "m", types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	iface := types.NewInterfaceType([]*types.Func{m}, nil).Complete()
+	sig := types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(types.NewVar(token.NoPos, nil, "", iface)), false)
+
+	for _, basePkg := range []string{"main", "example.com/a"} {
+		cs := NewConverterSet(typeset.New(nil), basePkg)
+		cs.SetReflectTypes([]*regexp.Regexp{regexp.MustCompile(".*")})
+		_ = cs.Add(sig, ToRye, "")
+		_, graph, _ := cs.genCode(false)
+		if basePkg == "main" {
+			require.ErrorIs(graph.Error(sig, ToRye), ErrUnexported)
+		} else {
+			// The method can be spelled in its own package
+			require.NoError(graph.Error(sig, ToRye))
+		}
+	}
+}
+
+func TestChunksFor(t *testing.T) {
+	require := require.New(t)
+
+	const src = `package a
+
+type T struct{ X int }
+
+func F(t *T) []string { return nil }
+func G(s []int) (*T, error) { return nil, nil }
+func H(f func() string) {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	require.NoError(err)
+	pkg, err := (&types.Config{}).Check("example.com/a", fset, []*ast.File{f}, nil)
+	require.NoError(err)
+
+	cs := NewConverterSet(typeset.New(func(p *types.Package) string { return p.Name() }), "main")
+	cs.SetNativeKindNames(map[string]string{"go(*a.T)": "go(*a.t)"})
+	var seeds []GraphNode
+	for _, name := range []string{"F", "G", "H"} {
+		typ := pkg.Scope().Lookup(name).Type()
+		cs.Add(typ, ToRye, name)
+		seeds = append(seeds, GraphNode{typ, ToRye})
+	}
+	chunks, graph, err := cs.Chunks()
+	require.NoError(err)
+
+	// Merging the chunks of each seed gives all chunks
+	merged := []Chunk{NativeKindNamesChunk(map[string]string{"go(*a.T)": "go(*a.t)"})}
+	seen := map[string]bool{}
+	var kinds []string
+	for _, seed := range seeds {
+		seedChunks, seedKinds := cs.ChunksFor(graph, []GraphNode{seed})
+		for _, c := range seedChunks {
+			require.NotEqual("nativeKindNames", c.Key)
+			if !seen[ChunkID(c)] {
+				seen[ChunkID(c)] = true
+				merged = append(merged, c)
+			}
+		}
+		kinds = append(kinds, seedKinds...)
+	}
+	SortChunks(merged)
+	require.Equal(chunks, merged)
+	require.Equal([]string{"go(*a.t)"}, graph.NativeKinds())
+	require.Contains(kinds, "go(*a.T)")
+
+	// Only the converters a seed depends on
+	hChunks, _ := cs.ChunksFor(graph, seeds[2:])
+	for _, c := range hChunks {
+		require.NotContains(c.Key, "a.T")
+	}
+}
@@ -50,12 +50,32 @@ func (e *ConverterError) sortedKeys() []convKey {
 	return slices.SortedFunc(maps.Keys(e.errors), convKey.cmp)
 }
 
-func (e *ConverterError) printSingleMessage(w io.Writer, k convKey) {
+// String returns a description of the converter, e.g. "convert int to Rye".
+func (k convKey) String() string {
 	dirStr := "to Rye"
 	if k.dir == FromRye {
 		dirStr = "from Rye"
 	}
-	fmt.Fprintf(w, "convert %v %v: %v", k.typString, dirStr, e.errors[k])
+	return fmt.Sprintf("convert %v %v", k.typString, dirStr)
+}
+
+// parseConvKey parses the result of [convKey.String].
+func parseConvKey(s string) (convKey, bool) {
+	s, ok := strings.CutPrefix(s, "convert ")
+	if !ok {
+		return convKey{}, false
+	}
+	if typString, ok := strings.CutSuffix(s, " to Rye"); ok {
+		return convKey{typString: typString, dir: ToRye}, true
+	}
+	if typString, ok := strings.CutSuffix(s, " from Rye"); ok {
+		return convKey{typString: typString, dir: FromRye}, true
+	}
+	return convKey{}, false
+}
+
+func (e *ConverterError) printSingleMessage(w io.Writer, k convKey) {
+	fmt.Fprintf(w, "%v: %v", k, e.errors[k])
 }
 
 // Error returns a short error message.
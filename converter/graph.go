@@ -5,6 +5,7 @@
 package converter
 
 import (
+	"errors"
 	"fmt"
 	"go/types"
 	"html"
@@ -56,7 +57,16 @@ type convGraph struct {
 // to the call stack (although it won't recurse endlessly).
 type calcNodeFunc func(ci convInfo, canConvert func(convInfo) bool) (code []byte, deps []convInfo, imports []*types.Package, err error)
 
-func makeConvGraph(seeds []convInfo, calcNode calcNodeFunc) convGraph {
+// prefetchFunc is optionally injected into makeConvGraph. It is
+// called with the nodes that are about to be calculated in a step
+// of the main graph traversal, so it can calculate them ahead of
+// time (e.g. in parallel). incomplete reports whether a node is
+// already known to be incomplete, and may be called concurrently.
+// calcNodeFunc is still called for each node in the usual order,
+// and must give the same results as without prefetching.
+type prefetchFunc func(batch []convInfo, incomplete func(convKey) bool)
+
+func makeConvGraph(seeds []convInfo, calcNode calcNodeFunc, prefetch prefetchFunc) convGraph {
 	// - A node represents a single converter with its conversion
 	//   code and dependencies.
 	// - Let "addNext" be the set of node keys to be calculated
@@ -151,10 +161,26 @@ func makeConvGraph(seeds []convInfo, calcNode calcNodeFunc) convGraph {
 	// Immediately calculates whether the given type is convertible.
 	var canConvert func(convInfo) bool
 
-	mainTraversal := func(startNodes []convInfo) {
+	// Nodes are only prefetched by the outermost traversal,
+	// since canConvert depends on the traversal stack.
+	mainTraversal := func(startNodes []convInfo, outermost bool) {
 		addNext := slices.Clone(startNodes)
 		var newAddNext []convInfo
 		for len(addNext) > 0 {
+			if prefetch != nil && outermost {
+				var batch []convInfo
+				seen := map[convKey]bool{}
+				for _, c := range addNext {
+					if _, ok := nodes[c.key]; !ok && !seen[c.key] {
+						seen[c.key] = true
+						batch = append(batch, c)
+					}
+				}
+				prefetch(batch, func(key convKey) bool {
+					n, ok := nodes[key]
+					return ok && n.incomplete
+				})
+			}
 			for _, c := range addNext {
 				if n, ok := nodes[c.key]; ok {
 					n.debugNames = append(n.debugNames, c.debugNames...)
@@ -241,7 +267,7 @@ func makeConvGraph(seeds []convInfo, calcNode calcNodeFunc) convGraph {
 				delete(processing, ci.key)
 			}()
 
-			mainTraversal([]convInfo{ci})
+			mainTraversal([]convInfo{ci}, false)
 			node, ok := nodes[ci.key]
 			if !ok {
 				panic("programmer error: expected explicitly traversed node to exist")
@@ -251,7 +277,7 @@ func makeConvGraph(seeds []convInfo, calcNode calcNodeFunc) convGraph {
 	}
 
 	// Main graph traversal (see topmost comment in this function).
-	mainTraversal(seeds)
+	mainTraversal(seeds, true)
 
 	// Clean up incomplete nodes and orphans.
 	resNodes := map[convKey]convNode{}
@@ -295,15 +321,17 @@ type GraphNode struct {
 // if the *Graph is nil.
 type Graph struct {
 	convGraph
-	typeSet    *typeset.TypeSet
-	sortedKeys []convKey
+	typeSet     *typeset.TypeSet
+	sortedKeys  []convKey
+	nativeKinds []string
 }
 
-func newGraph(cg convGraph, ts *typeset.TypeSet) *Graph {
+func newGraph(cg convGraph, ts *typeset.TypeSet, nativeKinds []string) *Graph {
 	return &Graph{
-		convGraph:  cg,
-		typeSet:    ts,
-		sortedKeys: slices.SortedFunc(maps.Keys(cg.nodes), convKey.cmp),
+		convGraph:   cg,
+		typeSet:     ts,
+		sortedKeys:  slices.SortedFunc(maps.Keys(cg.nodes), convKey.cmp),
+		nativeKinds: nativeKinds,
 	}
 }
 
@@ -322,6 +350,16 @@ func (g *Graph) Nodes() iter.Seq[GraphNode] {
 	}
 }
 
+// NativeKinds returns the sorted kind names of all Go natives
+// (e.g. "go(*net_http.Client)") the converters in the graph
+// can create.
+func (g *Graph) NativeKinds() []string {
+	if g == nil {
+		return nil
+	}
+	return g.nativeKinds
+}
+
 // Contains returns whether the graph contains a complete and valid
 // node with the given type and direction.
 func (g *Graph) Contains(t types.Type, dir Direction) bool {
@@ -332,6 +370,55 @@ func (g *Graph) Contains(t types.Type, dir Direction) bool {
 	return ok
 }
 
+// Error returns the reason why the graph contains no complete and
+// valid node with the given type and direction. The returned error
+// describes the converter the error originated from, which may be
+// a (possibly indirect) dependency of the requested one.
+// Returns nil if the graph contains the node.
+func (g *Graph) Error(t types.Type, dir Direction) error {
+	if g == nil {
+		return errors.New("no converter graph")
+	}
+	key := convKey{typString: g.typeSet.TypeString(t), dir: dir}
+	if _, ok := g.nodes[key]; ok {
+		return nil
+	}
+	origin, ok := g.errorOrigin(key)
+	if !ok {
+		return fmt.Errorf("%v: converter was never requested", key)
+	}
+	return fmt.Errorf("%v: %w", origin, g.debugNodes[origin].err)
+}
+
+// errorOrigin returns the key of the closest error node the
+// node with the given key depends on (or the node itself,
+// if it has an error).
+func (g convGraph) errorOrigin(key convKey) (convKey, bool) {
+	seen := map[convKey]bool{}
+	addNext := []convKey{key}
+	var newAddNext []convKey
+	for len(addNext) > 0 {
+		for _, k := range addNext {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			n, ok := g.debugNodes[k]
+			if !ok {
+				continue
+			}
+			if n.err != nil {
+				return k, true
+			}
+			for _, dep := range n.deps {
+				newAddNext = append(newAddNext, dep.key)
+			}
+		}
+		addNext, newAddNext = newAddNext, addNext[:0]
+	}
+	return convKey{}, false
+}
+
 // DebugDOTCode generates DOT (graphviz) code
 // for the converter dependency graph.
 // If nodeRe is nil, all nodes are included. If
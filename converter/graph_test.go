@@ -84,7 +84,7 @@ func graphBuilder(seeds []string, rules []rule) (run func() (_ convGraph, visite
 				return nil, depsCI, nil, nil
 			}
 			return nil, nil, nil, nil
-		})
+		}, nil)
 		return graph, visitedNodes
 	}
 }
@@ -192,6 +192,32 @@ Errors: <nil>
 	)
 }
 
+func TestErrorOrigin(t *testing.T) {
+	require := require.New(t)
+
+	graph, _ := graphBuilder(
+		[]string{"A", "B"},
+		[]rule{
+			{name: "A", deps: []string{"X", "string", "*A"}},
+			{name: "*A", deps: []string{"A"}},
+			{name: "X", deps: []string{"int"}},
+			{name: "int", err: errors.New("test")},
+			{name: "B", deps: []string{"string"}},
+		},
+	)()
+
+	origin, ok := graph.errorOrigin(convKey{typString: "A"})
+	require.True(ok)
+	require.Equal("int", origin.typString)
+
+	origin, ok = graph.errorOrigin(convKey{typString: "int"})
+	require.True(ok)
+	require.Equal("int", origin.typString)
+
+	_, ok = graph.errorOrigin(convKey{typString: "B"})
+	require.False(ok)
+}
+
 func FuzzMakeConvGraph(f *testing.F) {
 	f.Add(int64(0))
 	f.Fuzz(func(t *testing.T, seed int64) {
@@ -15,7 +15,10 @@ import (
 var templates embed.FS
 
 // Prelude code required by generated converters.
-const preludeCode = `import (
+const preludeCode = preludeImports + preludeBody
+
+// Imports used by the prelude and all converter templates.
+const preludeImports = `import (
 	_errors "errors"
 	_fmt "fmt"
 	_reflect "reflect"
@@ -25,7 +28,9 @@ const preludeCode = `import (
 	_evaldo "github.com/refaktor/rye/evaldo"
 	_sync "sync"
 )
+`
 
+const preludeBody = `
 // Force-use some packages so we don't have to track them.
 var _ = _errors.ErrUnsupported
 var _ = _evaldo.BuiltinNames
@@ -44,6 +49,15 @@ func objectType(ps *_env.ProgramState, v any) string {
 	}
 }
 
+// Adds entry as the name of the type name in package pkg
+// to typeLookup.
+func addTypeLookup(pkg, name, entry string) {
+	if typeLookup[pkg] == nil {
+		typeLookup[pkg] = map[string]string{}
+	}
+	typeLookup[pkg][name] = entry
+}
+
 // Attempts to look up the type of v. If the type is found, this
 // function returns an _env.Native of that type, true. If v's type
 // is not found in the lookup table, this function returns
@@ -67,6 +81,9 @@ func autoToNative(ps *_env.ProgramState, v any) (_ _env.Native, ok bool) {
 		return _env.Native{}, false
 	}
 	name := "go(" + _strings.Repeat("*", nPtrs) + entry + ")"
+	if renamed, ok := nativeKindNames[name]; ok {
+		name = renamed
+	}
 	return *_env.NewNative(ps.Idx, v, name), true
 }
 
@@ -83,6 +100,564 @@ func isNil(obj _env.Object) bool {
 	_, ok := obj.(_env.Void)
 	return ok
 }
+
+// Generic helpers instantiated by the converters of
+// composite types, so their code isn't repeated for
+// each element type. elemTyp and typ are type names
+// for error messages.
+
+func convSliceToRye[T any, R _env.Object](ps *_env.ProgramState, a []T, conv func(*_env.ProgramState, T) (R, error)) (_env.Block, error) {
+	items := make([]_env.Object, len(a))
+	for i := range a {
+		var err error
+		items[i], err = conv(ps, a[i])
+		if err != nil {
+			return _env.Block{}, err
+		}
+	}
+	return *_env.NewBlock(*_env.NewTSeries(items)), nil
+}
+
+func convSliceFromRye[S ~[]T, T any](ps *_env.ProgramState, obj _env.Object, elemTyp string, conv func(*_env.ProgramState, _env.Object) (T, error)) (S, error) {
+	if blk, ok := obj.(_env.Block); ok {
+		items := make(S, len(blk.Series.S))
+		for i, v := range blk.Series.S {
+			var err error
+			items[i], err = conv(ps, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	if nat, ok := obj.(_env.Native); ok {
+		if v, ok := nat.Value.(S); ok {
+			return v, nil
+		}
+	}
+	return nil, _errors.New("expected block of type " + elemTyp + ", but got " + objectType(ps, obj))
+}
+
+// Sets *res, of which items is a slice.
+func convArrayFromRye[A any, T any](ps *_env.ProgramState, obj _env.Object, res *A, items []T, elemTyp string, conv func(*_env.ProgramState, _env.Object) (T, error)) error {
+	if blk, ok := obj.(_env.Block); ok {
+		if len(blk.Series.S) != len(items) {
+			return _errors.New("expected block of type " + elemTyp + " to be of length " + _fmt.Sprint(len(items)) + ", but got " + objectType(ps, obj))
+		}
+		for i, v := range blk.Series.S {
+			var err error
+			items[i], err = conv(ps, v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if nat, ok := obj.(_env.Native); ok {
+		if v, ok := nat.Value.(A); ok {
+			*res = v
+			return nil
+		}
+	}
+	return _errors.New("expected block of type " + elemTyp + ", but got " + objectType(ps, obj))
+}
+
+func convPointerToRye[T any](ps *_env.ProgramState, p *T, kind string) (_env.Object, error) {
+	if p == nil {
+		return *_env.NewVoid(), nil
+	}
+	return *_env.NewNative(ps.Idx, p, kind), nil
+}
+
+func convPointerFromRye[T any](ps *_env.ProgramState, obj _env.Object, typ string, conv func(*_env.ProgramState, _env.Object) (T, error)) (*T, error) {
+	if isNil(obj) {
+		return nil, nil
+	}
+	// It's important to check for the pointer type first,
+	// since e.g. struct fromRye converters for non-pointers
+	// will also accept pointers and convert them to non-pointers.
+	if nat, ok := obj.(_env.Native); ok {
+		if v, ok := nat.Value.(*T); ok {
+			return v, nil
+		}
+	}
+	if x, err := conv(ps, obj); err == nil {
+		return &x, nil
+	}
+	return nil, _errors.New("expected Native of type " + typ + ", or any element type, but got " + objectType(ps, obj))
+}
+
+// Channels paired by chan converters, so converting
+// the same channel again gives the same channel.
+type chanInstances[K comparable, E any] struct {
+	mu   _sync.Mutex
+	live map[K]chan E
+}
+
+// Returns the channel paired with ch. If there is none, it
+// creates one and runs translate on both in a goroutine,
+// which keeps them paired until it returns.
+func (ci *chanInstances[K, E]) get(ch K, translate func(K, chan E)) chan E {
+	ci.mu.Lock()
+	res, have := ci.live[ch]
+	ci.mu.Unlock()
+	if !have {
+		res = make(chan E)
+		go func() {
+			ci.mu.Lock()
+			if ci.live == nil {
+				ci.live = map[K]chan E{}
+			}
+			ci.live[ch] = res
+			ci.mu.Unlock()
+			translate(ch, res)
+			ci.mu.Lock()
+			delete(ci.live, ch)
+			ci.mu.Unlock()
+		}()
+	}
+	return res
+}
+
+// Translates the values sent to ryeCh to send, and the values
+// received from recv to ryeCh, until either is closed. recv,
+// send and their converters are nil if the Go channel can't
+// receive or send.
+func translateChan[T any, R _env.Object](ps *_env.ProgramState, recv <-chan T, send chan<- T, ryeCh chan *_env.Object, elemTyp string, toRye func(*_env.ProgramState, T) (R, error), fromRye func(*_env.ProgramState, _env.Object) (T, error)) {
+	showError := func(err error) {
+		ps.FailureFlag = true
+		_fmt.Printf("Error from channel of type %v: %v\n", elemTyp, err)
+	}
+	for {
+		select {
+		case v, ok := <-ryeCh:
+			if !ok {
+				if send != nil {
+					close(send)
+				}
+				return
+			}
+			if send == nil {
+				showError(_errors.New("attempt to send to read-only Rye channel"))
+				continue
+			}
+			ov, err := fromRye(ps, *v)
+			if err != nil {
+				showError(err)
+				continue
+			}
+			send <- ov
+		case v, ok := <-recv:
+			if !ok {
+				close(ryeCh)
+				return
+			}
+			ov, err := toRye(ps, v)
+			if err != nil {
+				showError(err)
+				continue
+			}
+			ovObj := _env.Object(ov)
+			ryeCh <- &ovObj
+		}
+	}
+}
+`
+
+// Prelude code required by converters using reflection (see
+// [ConverterSet.SetReflectTypes]). Only generated if there are
+// any such converters.
+const reflectPreludeBody = `
+var (
+	reflectErrorType = _reflect.TypeOf((*error)(nil)).Elem()
+	reflectTimeType  = _reflect.TypeOf(_env.Time{}.Value)
+)
+
+// Returns the string of t as in the generated code, as far
+// as it can be derived using typeLookup.
+func reflectTypeString(t _reflect.Type) string {
+	if t.Name() != "" {
+		if entry, ok := typeLookup[t.PkgPath()][t.Name()]; ok {
+			return entry
+		}
+		return t.String()
+	}
+	switch t.Kind() {
+	case _reflect.Pointer:
+		return "*" + reflectTypeString(t.Elem())
+	case _reflect.Slice:
+		return "[]" + reflectTypeString(t.Elem())
+	case _reflect.Array:
+		return _fmt.Sprintf("[%v]%v", t.Len(), reflectTypeString(t.Elem()))
+	case _reflect.Map:
+		return "map[" + reflectTypeString(t.Key()) + "]" + reflectTypeString(t.Elem())
+	case _reflect.Chan:
+		switch t.ChanDir() {
+		case _reflect.RecvDir:
+			return "<-chan " + reflectTypeString(t.Elem())
+		case _reflect.SendDir:
+			return "chan<- " + reflectTypeString(t.Elem())
+		}
+		return "chan " + reflectTypeString(t.Elem())
+	}
+	return t.String()
+}
+
+// Creates a native holding v, which is of type t.
+func reflectNative(ps *_env.ProgramState, v any, t _reflect.Type) _env.Native {
+	kind := "go(" + reflectTypeString(t) + ")"
+	if renamed, ok := nativeKindNames[kind]; ok {
+		kind = renamed
+	}
+	return *_env.NewNative(ps.Idx, v, kind)
+}
+
+// Converts v to a Rye value using reflection, like the
+// generated converter of its type would.
+func reflectToRye(ps *_env.ProgramState, v _reflect.Value) (_env.Object, error) {
+	t := v.Type()
+	switch {
+	case t == reflectTimeType:
+		var res _env.Time
+		_reflect.ValueOf(&res.Value).Elem().Set(v)
+		return res, nil
+	case t == reflectErrorType:
+		if v.IsNil() {
+			return *_env.NewVoid(), nil
+		}
+		return _env.NewError(v.Interface().(error).Error()), nil
+	case t.Kind() == _reflect.Interface:
+		if v.IsNil() {
+			return *_env.NewVoid(), nil
+		}
+		if nat, ok := autoToNative(ps, v.Interface()); ok {
+			return nat, nil
+		}
+		if t.Name() == "" && t.NumMethod() == 0 {
+			return *_env.NewVoid(), _errors.New("expected go(any) to have a known type, but got " + objectType(ps, v.Interface()))
+		}
+		return reflectNative(ps, v.Interface(), t), nil
+	case t.Name() != "" && t.PkgPath() != "" || t.Kind() == _reflect.Struct:
+		// Passed by reference, so it can be modified
+		p := _reflect.New(t)
+		p.Elem().Set(v)
+		return reflectNative(ps, p.Interface(), p.Type()), nil
+	}
+	switch t.Kind() {
+	case _reflect.Bool:
+		return *_env.NewBoolean(v.Bool()), nil
+	case _reflect.Int, _reflect.Int8, _reflect.Int16, _reflect.Int32, _reflect.Int64:
+		return *_env.NewInteger(v.Int()), nil
+	case _reflect.Uint, _reflect.Uint8, _reflect.Uint16, _reflect.Uint32, _reflect.Uint64, _reflect.Uintptr:
+		return *_env.NewInteger(int64(v.Uint())), nil
+	case _reflect.Float32, _reflect.Float64:
+		return *_env.NewDecimal(v.Float()), nil
+	case _reflect.Complex64, _reflect.Complex128:
+		return *_env.NewComplex(v.Complex()), nil
+	case _reflect.String:
+		return *_env.NewString(v.String()), nil
+	case _reflect.Slice, _reflect.Array:
+		items := make([]_env.Object, v.Len())
+		for i := range items {
+			var err error
+			items[i], err = reflectToRye(ps, v.Index(i))
+			if err != nil {
+				return *_env.NewVoid(), err
+			}
+		}
+		return *_env.NewBlock(*_env.NewTSeries(items)), nil
+	case _reflect.Map:
+		if t.Key() != _reflect.TypeOf("") {
+			return reflectNative(ps, v.Interface(), t), nil
+		}
+		data := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			val, err := reflectToRye(ps, it.Value())
+			if err != nil {
+				return *_env.NewVoid(), err
+			}
+			data[it.Key().String()] = val
+		}
+		return *_env.NewDict(data), nil
+	case _reflect.Pointer, _reflect.Chan:
+		if v.IsNil() {
+			return *_env.NewVoid(), nil
+		}
+		return reflectNative(ps, v.Interface(), t), nil
+	case _reflect.UnsafePointer:
+		return reflectNative(ps, v.Interface(), t), nil
+	case _reflect.Func:
+		return reflectFuncToRye(v), nil
+	}
+	return *_env.NewVoid(), _errors.New("can't convert value of type " + reflectTypeString(t) + " to Rye")
+}
+
+// Converts the func fn to a Rye builtin using reflection.
+func reflectFuncToRye(fn _reflect.Value) _env.VarBuiltin {
+	return _env.VarBuiltin{
+		Argsn: fn.Type().NumIn(),
+		Fn: func(ps *_env.ProgramState, args ..._env.Object) _env.Object {
+			res, err := reflectCall(ps, fn, args)
+			if err != nil {
+				ps.FailureFlag = true
+				return _env.NewError(err.Error())
+			}
+			return res
+		},
+	}
+}
+
+// Calls fn with args converted to its parameter types, and
+// returns its results converted to Rye.
+func reflectCall(ps *_env.ProgramState, fn _reflect.Value, args []_env.Object) (_env.Object, error) {
+	t := fn.Type()
+	in := make([]_reflect.Value, t.NumIn())
+	for i := range in {
+		in[i] = _reflect.New(t.In(i)).Elem()
+		if err := reflectFromRye(ps, args[i], in[i]); err != nil {
+			return *_env.NewVoid(), err
+		}
+	}
+	var out []_reflect.Value
+	if t.IsVariadic() {
+		out = fn.CallSlice(in)
+	} else {
+		out = fn.Call(in)
+	}
+	if n := len(out); n > 0 && t.Out(n-1) == reflectErrorType {
+		if !out[n-1].IsNil() {
+			return *_env.NewVoid(), out[n-1].Interface().(error)
+		}
+		out = out[:n-1]
+	}
+	res := make([]_env.Object, len(out))
+	for i := range out {
+		var err error
+		res[i], err = reflectToRye(ps, out[i])
+		if err != nil {
+			return *_env.NewVoid(), err
+		}
+	}
+	switch len(res) {
+	case 0:
+		return *_env.NewVoid(), nil
+	case 1:
+		return res[0], nil
+	}
+	return *_env.NewBlock(*_env.NewTSeries(res)), nil
+}
+
+// Sets v to obj converted using reflection, like the
+// generated converter of its type would.
+func reflectFromRye(ps *_env.ProgramState, obj _env.Object, v _reflect.Value) error {
+	t := v.Type()
+	if nat, ok := obj.(_env.Native); ok && nat.Value != nil {
+		nv := _reflect.ValueOf(nat.Value)
+		if nv.Type().AssignableTo(t) {
+			v.Set(nv)
+			return nil
+		}
+		// Named types and structs are passed by reference
+		if nv.Kind() == _reflect.Pointer && nv.Type().Elem() == t && !nv.IsNil() {
+			v.Set(nv.Elem())
+			return nil
+		}
+	}
+	switch t.Kind() {
+	case _reflect.Pointer, _reflect.Interface, _reflect.Map, _reflect.Slice, _reflect.Func, _reflect.Chan:
+		if isNil(obj) {
+			v.Set(_reflect.Zero(t))
+			return nil
+		}
+	}
+	switch {
+	case t == reflectTimeType:
+		if x, ok := obj.(_env.Time); ok {
+			v.Set(_reflect.ValueOf(x.Value))
+			return nil
+		}
+	case t == reflectErrorType:
+		if x, ok := obj.(_env.Error); ok {
+			v.Set(_reflect.ValueOf(_errors.New(x.Print(*ps.Idx))))
+			return nil
+		}
+	}
+	switch t.Kind() {
+	case _reflect.Bool:
+		if x, ok := obj.(_env.Boolean); ok {
+			v.SetBool(x.Value)
+			return nil
+		}
+	case _reflect.Int, _reflect.Int8, _reflect.Int16, _reflect.Int32, _reflect.Int64:
+		if x, ok := obj.(_env.Integer); ok {
+			v.SetInt(x.Value)
+			return nil
+		}
+	case _reflect.Uint, _reflect.Uint8, _reflect.Uint16, _reflect.Uint32, _reflect.Uint64, _reflect.Uintptr:
+		if x, ok := obj.(_env.Integer); ok {
+			v.SetUint(uint64(x.Value))
+			return nil
+		}
+	case _reflect.Float32, _reflect.Float64:
+		if x, ok := obj.(_env.Decimal); ok {
+			v.SetFloat(x.Value)
+			return nil
+		}
+	case _reflect.Complex64, _reflect.Complex128:
+		if x, ok := obj.(_env.Complex); ok {
+			v.SetComplex(x.Value)
+			return nil
+		}
+	case _reflect.String:
+		if x, ok := obj.(_env.String); ok {
+			v.SetString(x.Value)
+			return nil
+		}
+	case _reflect.Slice:
+		if x, ok := obj.(_env.String); ok && t.Elem() == _reflect.TypeOf(byte(0)) {
+			v.Set(_reflect.ValueOf([]byte(x.Value)).Convert(t))
+			return nil
+		}
+		if blk, ok := obj.(_env.Block); ok {
+			items := _reflect.MakeSlice(t, len(blk.Series.S), len(blk.Series.S))
+			for i, item := range blk.Series.S {
+				if err := reflectFromRye(ps, item, items.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(items)
+			return nil
+		}
+	case _reflect.Array:
+		if blk, ok := obj.(_env.Block); ok {
+			if len(blk.Series.S) != t.Len() {
+				return _errors.New(_fmt.Sprintf("expected block of type %v to be of length %v, but got ", reflectTypeString(t.Elem()), t.Len()) + objectType(ps, obj))
+			}
+			items := _reflect.New(t).Elem()
+			for i, item := range blk.Series.S {
+				if err := reflectFromRye(ps, item, items.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(items)
+			return nil
+		}
+	case _reflect.Pointer:
+		p := _reflect.New(t.Elem())
+		if err := reflectFromRye(ps, obj, p.Elem()); err == nil {
+			v.Set(p)
+			return nil
+		}
+		return _errors.New("expected Native of type " + reflectTypeString(t) + ", or any element type, but got " + objectType(ps, obj))
+	case _reflect.Interface:
+		if t.NumMethod() == 0 {
+			var x any
+			switch o := obj.(type) {
+			case _env.Boolean:
+				x = o.Value
+			case _env.Complex:
+				x = o.Value
+			case _env.Date:
+				x = o.Value
+			case _env.Decimal:
+				x = o.Value
+			case _env.Email:
+				x = o.Address
+			case _env.Error:
+				x = _errors.New(o.Print(*ps.Idx))
+			case _env.Integer:
+				x = o.Value
+			case _env.Native:
+				x = o.Value
+			case _env.String:
+				x = o.Value
+			case _env.Time:
+				x = o.Value
+			default:
+				return _errors.New("expected primitive or Native, but got " + objectType(ps, obj))
+			}
+			if x == nil {
+				v.Set(_reflect.Zero(t))
+			} else {
+				v.Set(_reflect.ValueOf(x))
+			}
+			return nil
+		}
+	case _reflect.Struct:
+		if ctx, ok := obj.(_env.RyeCtx); ok {
+			res := _reflect.New(t).Elem()
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if !f.IsExported() {
+					continue
+				}
+				if idx, ok := ps.Idx.GetIndex(f.Name); ok {
+					if val, ok := ctx.Get(idx); ok {
+						if err := reflectFromRye(ps, val, res.Field(i)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			v.Set(res)
+			return nil
+		}
+	case _reflect.Func:
+		if fn, ok := obj.(_env.Function); ok {
+			if fn.Argsn != t.NumIn() {
+				return _errors.New(_fmt.Sprintf("expected function with %v args, but got ", t.NumIn()) + objectType(ps, obj))
+			}
+			v.Set(_reflect.MakeFunc(t, func(in []_reflect.Value) []_reflect.Value {
+				zero := make([]_reflect.Value, t.NumOut())
+				for i := range zero {
+					zero[i] = _reflect.Zero(t.Out(i))
+				}
+				args := make([]_env.Object, len(in))
+				for i := range in {
+					var err error
+					args[i], err = reflectToRye(ps, in[i])
+					if err != nil {
+						showFunctionError(ps, fn, err)
+						return zero
+					}
+				}
+				_evaldo.CallFunctionArgsN(fn, ps, ps.Ctx, args...)
+				if e, ok := ps.Res.(*_env.Error); ok {
+					showFunctionError(ps, fn, _errors.New(e.Message))
+					return zero
+				}
+				results := []_env.Object{ps.Res}
+				switch t.NumOut() {
+				case 0:
+					return zero
+				case 1:
+				default:
+					blk, ok := ps.Res.(_env.Block)
+					if !ok {
+						showFunctionError(ps, fn, _errors.New("expected block with results, but got "+objectType(ps, ps.Res)))
+						return zero
+					}
+					if len(blk.Series.S) != t.NumOut() {
+						showFunctionError(ps, fn, _fmt.Errorf("expected %v results, but got %v", t.NumOut(), len(blk.Series.S)))
+						return zero
+					}
+					results = blk.Series.S
+				}
+				out := make([]_reflect.Value, t.NumOut())
+				for i := range out {
+					out[i] = _reflect.New(t.Out(i)).Elem()
+					if err := reflectFromRye(ps, results[i], out[i]); err != nil {
+						showFunctionError(ps, fn, err)
+						return zero
+					}
+				}
+				return out
+			}))
+			return nil
+		}
+	}
+	return _errors.New("expected " + reflectTypeString(t) + ", but got " + objectType(ps, obj))
+}
 `
 
 var templateFuncMap = template.FuncMap{
@@ -113,6 +688,17 @@ var templateFuncMap = template.FuncMap{
 	//
 	// Dynamically generated for dependency tracking.
 	"typStr": (func(typ types.Type) string)(nil),
+	// Returns the Rye kind name of a Go native holding a
+	// value of type typ, e.g. "go(*net_http.Client)".
+	// Invoking this function will mark the type as an
+	// import dependency of the converter it was invoked from,
+	// and the kind as one created by the converter.
+	//
+	// Same as with typStr, always use the "quote" function
+	// to put the result into a string literal.
+	//
+	// Dynamically generated for dependency tracking.
+	"nativeKind": (func(typ types.Type) string)(nil),
 	// Returns a unique string hash for the given type.
 	// You MUST prefix this with a usage (e.g. iface_) and a
 	// direction so it doesn't get mixed up with typHashes
@@ -3,42 +3,7 @@ func conv_int_toRye(ps *_env.ProgramState, x int) (_env.Integer, error) {
 	return *_env.NewInteger(int64(x)), nil
 }
 
-func translateChan_202e8713191152c4(ps *_env.ProgramState, goCh chan int, ryeCh chan *_env.Object) {
-    showError := func(err error) {
-        ps.FailureFlag = true
-        _fmt.Printf("Error from channel of type %v: %v\n", "int", err)
-    }
-    for {
-        select {
-        case v, ok := <-ryeCh:
-            if !ok {
-                close(goCh)
-                return
-            }
-            ov, err := conv_int_fromRye(ps, *v)
-            if err != nil {
-                showError(err)
-                continue
-            }
-            goCh <- ov
-        case v, ok := <-goCh:
-            if !ok {
-                close(ryeCh)
-                return
-            }
-            ov, err := conv_int_toRye(ps, v)
-            if err != nil {
-                showError(err)
-                continue
-            }
-            ovObj := _env.Object(ov)
-            ryeCh <- &ovObj
-        }
-    }
-}
-
-var chanInstances_202e8713191152c4_fromRye_live = map[chan *_env.Object]chan int{}
-var chanInstances_202e8713191152c4_fromRye_mu _sync.Mutex
+var chanInstances_202e8713191152c4_fromRye chanInstances[chan *_env.Object, int]
 
 func conv_chan_sr_int_fromRye(ps *_env.ProgramState, obj _env.Object) (chan int, error) {
 	if isNil(obj) {
@@ -46,22 +11,9 @@ func conv_chan_sr_int_fromRye(ps *_env.ProgramState, obj _env.Object) (chan int,
 	}
 	if nat, ok := obj.(_env.Native); ok {
 		if ryeCh, ok := nat.Value.(chan *_env.Object); ok {
-			chanInstances_202e8713191152c4_fromRye_mu.Lock()
-			goCh, have := chanInstances_202e8713191152c4_fromRye_live[ryeCh]
-			chanInstances_202e8713191152c4_fromRye_mu.Unlock()
-			if !have {
-				goCh = make(chan int)
-				go func() {
-					chanInstances_202e8713191152c4_fromRye_mu.Lock()
-					chanInstances_202e8713191152c4_fromRye_live[ryeCh] = goCh
-					chanInstances_202e8713191152c4_fromRye_mu.Unlock()
-					translateChan_202e8713191152c4(ps, goCh, ryeCh)
-					chanInstances_202e8713191152c4_fromRye_mu.Lock()
-					delete(chanInstances_202e8713191152c4_fromRye_live, ryeCh)
-					chanInstances_202e8713191152c4_fromRye_mu.Unlock()
-				}()
-			}
-			return goCh, nil
+			return chanInstances_202e8713191152c4_fromRye.get(ryeCh, func(ryeCh chan *_env.Object, goCh chan int) {
+				translateChan(ps, goCh, goCh, ryeCh, "int", conv_int_toRye, conv_int_fromRye)
+			}), nil
 		}
 	}
 	if nat, ok := obj.(_env.Native); ok {
@@ -0,0 +1,8 @@
+type struct_4098967942f0bbcd = struct{A []int; B map[string]int}
+
+var typeLookup = map[string]map[string]string{}
+func conv_struct_4098967942f0bbcd_fromRye(ps *_env.ProgramState, obj _env.Object) (struct_4098967942f0bbcd, error) {
+	var res struct_4098967942f0bbcd
+	err := reflectFromRye(ps, obj, _reflect.ValueOf(&res).Elem())
+	return res, err
+}
\ No newline at end of file
@@ -35,23 +35,7 @@ func conv_func_233d01fb786b160a_toRye(ps *_env.ProgramState, fn func(a int, b in
 }
 
 func conv_slice_string_fromRye(ps *_env.ProgramState, obj _env.Object) ([]string, error) {
-	if blk, ok := obj.(_env.Block); ok {
-		items := make([]string, len(blk.Series.S))
-		for i, v := range blk.Series.S {
-			var err error
-			items[i], err = conv_string_fromRye(ps, v)
-			if err != nil {
-				return nil, err
-			}
-		}
-		return items, nil
-	}
-	if nat, ok := obj.(_env.Native); ok {
-		if v, ok := nat.Value.([]string); ok {
-			return v, nil
-		}
-	}
-	return nil, _errors.New("expected block of type " + "string" + ", but got " + objectType(ps, obj))
+	return convSliceFromRye[[]string](ps, obj, "string", conv_string_fromRye)
 }
 
 func conv_int_fromRye(ps *_env.ProgramState, obj _env.Object) (int, error) {
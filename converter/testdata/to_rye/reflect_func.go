@@ -0,0 +1,9 @@
+var typeLookup = map[string]map[string]string{}
+func init() {
+	typeLookup[""] = map[string]string{}
+	typeLookup[""]["error"] = "error"
+}
+
+func conv_func_d239ee2fd43707cc_toRye(ps *_env.ProgramState, fn func(a int) (string, error)) (_env.VarBuiltin, error) {
+	return reflectFuncToRye(_reflect.ValueOf(fn)), nil
+}
\ No newline at end of file
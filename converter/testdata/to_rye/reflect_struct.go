@@ -0,0 +1,6 @@
+type struct_4098967942f0bbcd = struct{A []int; B map[string]int}
+
+var typeLookup = map[string]map[string]string{}
+func conv_struct_4098967942f0bbcd_toRye(ps *_env.ProgramState, x struct_4098967942f0bbcd) (_env.Object, error) {
+	return reflectToRye(ps, _reflect.ValueOf(&x).Elem())
+}
\ No newline at end of file
@@ -69,7 +69,7 @@ func typeHash(s string) string {
 // structs.
 // Type strings and normalized types are recorded into
 // the ts's cache and created aliases are recorded into ts's
-// aliases.
+// aliases. ts.mu must be locked.
 func (ts *TypeSet) normalizeAndAddType(typ types.Type) types.Type {
 	unnormalizedType := typ
 
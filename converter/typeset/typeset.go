@@ -17,6 +17,7 @@ import (
 	"iter"
 	"maps"
 	"slices"
+	"sync"
 )
 
 // TypeSet handles stringification and automatic aliasing of types.
@@ -27,7 +28,12 @@ import (
 // TypeSet also automatically creates aliases for struct types
 // so that their strings are kept small. You can query all
 // created aliases with [TypeSet.Aliases].
+//
+// TypeSet is safe for concurrent use. The alias names only
+// depend on the struct types, so they don't depend on the
+// order of calls.
 type TypeSet struct {
+	mu        sync.RWMutex // guards the caches and aliases
 	qualifier types.Qualifier
 	nameCache map[types.Type]string     // unnormalized type to normalized string
 	normCache map[types.Type]types.Type // unnormalized type to normalized
@@ -48,6 +54,21 @@ func New(qualifier types.Qualifier) *TypeSet {
 // The string and normalized type are cached for future calls,
 // and any aliases required are registered.
 func (ts *TypeSet) TypeString(t types.Type) string {
+	ts.mu.RLock()
+	s, ok := ts.nameCache[t]
+	ts.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.typeString(t)
+}
+
+// typeString is like [TypeSet.TypeString], but ts.mu
+// must be locked.
+func (ts *TypeSet) typeString(t types.Type) string {
 	if s, ok := ts.nameCache[t]; ok {
 		return s
 	}
@@ -69,18 +90,27 @@ func (ts *TypeSet) ContainsAlias(t types.Type) bool {
 	if _, ok := t.(*types.Alias); !ok {
 		return false
 	}
-	s := ts.TypeString(t)
-	_, ok := ts.aliases[s]
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	_, ok := ts.aliases[ts.typeString(t)]
 	return ok
 }
 
 // Normalized returns the normalized version of typ.
 // Cached for future calls.
 func (ts *TypeSet) Normalized(typ types.Type) types.Type {
-	if t, ok := ts.normCache[typ]; ok {
+	ts.mu.RLock()
+	t, ok := ts.normCache[typ]
+	ts.mu.RUnlock()
+	if ok {
 		return t
 	}
 
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if t, ok := ts.normCache[typ]; ok {
+		return t
+	}
 	return ts.normalizeAndAddType(typ)
 }
 
@@ -98,9 +128,14 @@ type Alias struct {
 // obtain the code for the referenced struct.
 func (ts *TypeSet) Aliases() iter.Seq[Alias] {
 	return func(yield func(Alias) bool) {
-		keys := slices.Sorted(maps.Keys(ts.aliases))
-		for _, key := range keys {
-			if !yield(Alias{key, ts.aliases[key]}) {
+		ts.mu.RLock()
+		aliases := make([]Alias, 0, len(ts.aliases))
+		for _, key := range slices.Sorted(maps.Keys(ts.aliases)) {
+			aliases = append(aliases, Alias{key, ts.aliases[key]})
+		}
+		ts.mu.RUnlock()
+		for _, alias := range aliases {
+			if !yield(alias) {
 				break
 			}
 		}
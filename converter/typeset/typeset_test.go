@@ -5,6 +5,7 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -162,3 +163,42 @@ type Nested = struct {
 		)
 	}
 }
+
+func TestTypesetConcurrent(t *testing.T) {
+	require := require.New(t)
+	pkg := parse(t, `
+package main
+
+var A struct {
+	X int
+	Z struct {
+		P float32
+	}
+}
+
+var B func(struct{ P float32 }) *struct{ X int }
+`)
+	want := New(nil)
+	wantA := want.TypeString(pkg.Lookup("A").Type())
+	wantB := want.TypeString(pkg.Lookup("B").Type())
+
+	ts := New(nil)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Equal(wantB, ts.TypeString(pkg.Lookup("B").Type()))
+			require.Equal(wantA, ts.TypeString(pkg.Lookup("A").Type()))
+		}()
+	}
+	wg.Wait()
+	aliasNames := func(ts *TypeSet) []string {
+		var res []string
+		for a := range ts.Aliases() {
+			res = append(res, a.Name)
+		}
+		return res
+	}
+	require.Equal(aliasNames(want), aliasNames(ts))
+}
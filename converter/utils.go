@@ -2,8 +2,13 @@ package converter
 
 import (
 	"cmp"
+	"go/scanner"
+	"go/token"
 	"go/types"
+	"runtime"
 	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/refaktor/ryegen/v2/converter/typeset"
 )
@@ -44,3 +49,53 @@ func sortedUniq[T any, S ~[]T](s S, compare func(T, T) int) S {
 		return compare(a, b) == 0
 	})
 }
+
+// usedImports returns the packages of imports that code refers
+// to with their qualifier. Type names that only appear in string
+// literals (e.g. error messages) don't count.
+func usedImports(code []byte, imports []*types.Package, qualifier types.Qualifier) []*types.Package {
+	used := map[string]bool{}
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(code)), code, nil, 0)
+	var prevLit string
+	prevTok := token.ILLEGAL
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.PERIOD && prevTok == token.IDENT {
+			used[prevLit] = true
+		}
+		prevTok, prevLit = tok, lit
+	}
+	return slices.DeleteFunc(slices.Clone(imports), func(pkg *types.Package) bool {
+		return !used[qualifier(pkg)]
+	})
+}
+
+// parallelFor calls a work func for each i in [0, n) and waits
+// until all calls have returned. The calls are distributed over
+// up to GOMAXPROCS goroutines, each of which gets its work func
+// from newWorker, so workers can have their own state.
+func parallelFor(n int, newWorker func() func(i int)) {
+	workers := min(n, runtime.GOMAXPROCS(0))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work := newWorker()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n {
+					return
+				}
+				work(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,41 @@
+package main
+
+import "strings"
+
+// splitCommon splits the items of outs into the ones present
+// (with the same id) in all of outs and the remaining ones of
+// each of outs. The order of the items is preserved.
+func splitCommon[T any](outs [][]T, id func(T) string) (common []T, rest [][]T) {
+	counts := map[string]int{}
+	for _, out := range outs {
+		seen := map[string]bool{}
+		for _, x := range out {
+			if k := id(x); !seen[k] {
+				seen[k] = true
+				counts[k]++
+			}
+		}
+	}
+	rest = make([][]T, len(outs))
+	for i, out := range outs {
+		for _, x := range out {
+			if counts[id(x)] == len(outs) {
+				if i == 0 {
+					common = append(common, x)
+				}
+			} else {
+				rest[i] = append(rest[i], x)
+			}
+		}
+	}
+	return common, rest
+}
+
+// unionBuildExpr returns a build constraint expression that is
+// satisfied if any of exprs is.
+func unionBuildExpr(exprs []string) string {
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return "(" + strings.Join(exprs, ") || (") + ")"
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommon(t *testing.T) {
+	require := require.New(t)
+
+	id := func(s string) string { return s }
+	common, rest := splitCommon([][]string{
+		{"a", "b", "c", "d"},
+		{"a", "c", "e"},
+		{"c", "a", "d"},
+	}, id)
+	require.Equal([]string{"a", "c"}, common)
+	require.Equal([][]string{{"b", "d"}, {"e"}, {"d"}}, rest)
+
+	common, rest = splitCommon([][]string{{"a", "b"}}, id)
+	require.Equal([]string{"a", "b"}, common)
+	require.Equal([][]string{nil}, rest)
+}
+
+func TestUnionBuildExpr(t *testing.T) {
+	require := require.New(t)
+	require.Equal("linux && amd64", unionBuildExpr([]string{"linux && amd64"}))
+	require.Equal("(linux && amd64) || (darwin && arm64 && cgo)", unionBuildExpr([]string{"linux && amd64", "darwin && arm64 && cgo"}))
+}
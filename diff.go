@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// readManifest decodes a manifest written by Ryegen.
+func readManifest(data []byte) (manifest, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, err
+	}
+	if m.Generator != "ryegen" {
+		return manifest{}, fmt.Errorf("not a ryegen manifest")
+	}
+	if m.Version > manifestVersion {
+		return manifest{}, fmt.Errorf("unsupported manifest version %v (newest supported is %v)", m.Version, manifestVersion)
+	}
+	return m, nil
+}
+
+// loadPreviousManifest loads the bindings of a previous run to
+// compare against. If path is empty, the manifest at manifestPath
+// is used, falling back to the generated builtins file at
+// builtinsPath and its per-package part files at partPaths.
+// Returns the file the bindings were loaded from.
+func loadPreviousManifest(path, manifestPath, builtinsPath string, partPaths []string) (m manifest, from string, err error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return manifest{}, "", err
+		}
+		m, err := readManifest(data)
+		if err != nil {
+			return manifest{}, "", fmt.Errorf("%v: %w", path, err)
+		}
+		return m, path, nil
+	}
+
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		m, err := readManifest(data)
+		if err != nil {
+			return manifest{}, "", fmt.Errorf("%v: %w", manifestPath, err)
+		}
+		return m, manifestPath, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return manifest{}, "", err
+	}
+
+	data, err := os.ReadFile(builtinsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return manifest{}, "", fmt.Errorf("neither %v nor %v found", manifestPath, builtinsPath)
+		}
+		return manifest{}, "", err
+	}
+	for _, p := range partPaths {
+		part, err := os.ReadFile(p)
+		if err != nil {
+			return manifest{}, "", err
+		}
+		data = append(data, part...)
+	}
+	return manifestFromBuiltinsFile(data), builtinsPath, nil
+}
+
+var (
+	reBuiltinsPkg   = regexp.MustCompile(`^\tbuiltins\[("(?:[^"\\]|\\.)*")\] = (builtins_\w+)$`)
+	reBuiltinsChunk = regexp.MustCompile(`^\tm := (builtins_\w+)$`)
+	reBuiltinsKey   = regexp.MustCompile(`^\t\t?m\[("(?:[^"\\]|\\.)*")\] = `)
+	reBuiltinsAlias = regexp.MustCompile(`^\t\t?m\[("(?:[^"\\]|\\.)*")\] = (deprecatedAlias\()?m\[("(?:[^"\\]|\\.)*")\]`)
+	// Since builtins are initialized lazily
+	reBuiltinsAdd = regexp.MustCompile(`^\taddBuiltins\(("(?:[^"\\]|\\.)*"), func\(`)
+)
+
+// manifestFromBuiltinsFile reconstructs a partial manifest from
+// a generated ryegen_builtins_*.gen.go file. Only package, key,
+// receiver, name and aliases of each binding are known, so the
+// manifest can only be used to detect added and removed bindings.
+func manifestFromBuiltinsFile(data []byte) manifest {
+	mapToPkg := map[string]string{}
+	mapToKeys := map[string][]string{}
+	type alias struct {
+		key, of    string
+		deprecated bool
+	}
+	mapToAliases := map[string][]alias{}
+	var currMap string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		line := sc.Text()
+		if m := reBuiltinsPkg.FindStringSubmatch(line); m != nil {
+			if pkg, err := strconv.Unquote(m[1]); err == nil {
+				mapToPkg[m[2]] = pkg
+			}
+		} else if m := reBuiltinsChunk.FindStringSubmatch(line); m != nil {
+			currMap = m[1]
+		} else if m := reBuiltinsAdd.FindStringSubmatch(line); m != nil {
+			if pkg, err := strconv.Unquote(m[1]); err == nil {
+				currMap = "addBuiltins " + pkg
+				mapToPkg[currMap] = pkg
+			}
+		} else if m := reBuiltinsAlias.FindStringSubmatch(line); m != nil {
+			key, err1 := strconv.Unquote(m[1])
+			of, err2 := strconv.Unquote(m[3])
+			if err1 == nil && err2 == nil {
+				mapToAliases[currMap] = append(mapToAliases[currMap], alias{key: key, of: of, deprecated: m[2] != ""})
+			}
+		} else if m := reBuiltinsKey.FindStringSubmatch(line); m != nil {
+			if key, err := strconv.Unquote(m[1]); err == nil {
+				mapToKeys[currMap] = append(mapToKeys[currMap], key)
+			}
+		}
+	}
+
+	res := manifest{
+		Generator: "ryegen",
+		Version:   manifestVersion,
+	}
+	for mapName, keys := range mapToKeys {
+		aliases := map[string][]manifestAlias{}
+		for _, a := range mapToAliases[mapName] {
+			_, name, ok := strings.Cut(a.key, "//")
+			if !ok {
+				name = a.key
+			}
+			aliases[a.of] = append(aliases[a.of], manifestAlias{Name: name, Key: a.key, Deprecated: a.deprecated})
+		}
+		for _, key := range keys {
+			b := manifestBinding{
+				Package: mapToPkg[mapName],
+				Name:    key,
+				Key:     key,
+				Aliases: aliases[key],
+			}
+			if recv, name, ok := strings.Cut(key, "//"); ok {
+				b.Receiver = recv
+				b.Name = name
+			}
+			res.Bindings = append(res.Bindings, b)
+		}
+	}
+	return res
+}
+
+// bindingRename is a binding whose Rye name changed,
+// but whose Go symbol stayed the same.
+type bindingRename struct {
+	old, new manifestBinding
+}
+
+// bindingChange is a binding whose Rye name stayed the
+// same, but whose type or signature changed.
+type bindingChange struct {
+	old, new manifestBinding
+}
+
+// packageDiff holds the binding changes in a single Rye package.
+type packageDiff struct {
+	added   []manifestBinding
+	removed []manifestBinding
+	renamed []bindingRename
+	changed []bindingChange
+}
+
+// manifestDiff maps Rye package paths to their changes.
+// Packages without changes are not included.
+type manifestDiff map[string]*packageDiff
+
+// breaking returns whether any existing binding was
+// removed or renamed.
+func (d manifestDiff) breaking() bool {
+	for _, pd := range d {
+		if len(pd.removed) > 0 || len(pd.renamed) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// diffManifests compares the bindings of two manifests.
+//
+// Bindings that vanished and appeared with the same Go symbol
+// and binding type in the same package are reported as renamed.
+// Signatures are only compared if both manifests contain
+// them (see [manifestFromBuiltinsFile]). Aliases are compared
+// like bindings, so removing one is a breaking change too.
+func diffManifests(old, new manifest) manifestDiff {
+	type pkgKey struct{ pkg, key string }
+	index := func(m manifest) map[pkgKey]manifestBinding {
+		res := make(map[pkgKey]manifestBinding, len(m.Bindings))
+		for _, b := range m.Bindings {
+			res[pkgKey{b.Package, b.Key}] = b
+			for _, a := range b.Aliases {
+				res[pkgKey{b.Package, a.Key}] = manifestBinding{
+					Package:  b.Package,
+					Receiver: b.Receiver,
+					Name:     a.Name,
+					Key:      a.Key,
+					aliasOf:  b.Key,
+				}
+			}
+		}
+		return res
+	}
+	oldIdx, newIdx := index(old), index(new)
+
+	diff := manifestDiff{}
+	pkgDiff := func(pkg string) *packageDiff {
+		if diff[pkg] == nil {
+			diff[pkg] = &packageDiff{}
+		}
+		return diff[pkg]
+	}
+
+	// Added bindings by package, Go symbol and binding type;
+	// used to detect renames.
+	type symKey struct{ pkg, goSymbol, typ string }
+	added := map[symKey][]manifestBinding{}
+	for _, k := range slices.SortedFunc(maps.Keys(newIdx), func(a, b pkgKey) int {
+		return cmp.Or(cmp.Compare(a.pkg, b.pkg), cmp.Compare(a.key, b.key))
+	}) {
+		if _, ok := oldIdx[k]; !ok {
+			b := newIdx[k]
+			sk := symKey{b.Package, b.GoSymbol, b.Type}
+			added[sk] = append(added[sk], b)
+		}
+	}
+
+	for _, k := range slices.SortedFunc(maps.Keys(oldIdx), func(a, b pkgKey) int {
+		return cmp.Or(cmp.Compare(a.pkg, b.pkg), cmp.Compare(a.key, b.key))
+	}) {
+		oldB := oldIdx[k]
+		if newB, ok := newIdx[k]; ok {
+			if signatureKnown(oldB) && signatureKnown(newB) && !sameSignature(oldB, newB) {
+				pd := pkgDiff(k.pkg)
+				pd.changed = append(pd.changed, bindingChange{old: oldB, new: newB})
+			}
+			continue
+		}
+		sk := symKey{oldB.Package, oldB.GoSymbol, oldB.Type}
+		if oldB.GoSymbol != "" && len(added[sk]) > 0 {
+			pd := pkgDiff(k.pkg)
+			pd.renamed = append(pd.renamed, bindingRename{old: oldB, new: added[sk][0]})
+			added[sk] = added[sk][1:]
+			continue
+		}
+		pd := pkgDiff(k.pkg)
+		pd.removed = append(pd.removed, oldB)
+	}
+
+	for _, bs := range added {
+		for _, b := range bs {
+			pd := pkgDiff(b.Package)
+			pd.added = append(pd.added, b)
+		}
+	}
+	for _, pd := range diff {
+		slices.SortFunc(pd.added, func(a, b manifestBinding) int {
+			return cmp.Compare(a.Key, b.Key)
+		})
+	}
+
+	return diff
+}
+
+func signatureKnown(b manifestBinding) bool {
+	return b.Type != ""
+}
+
+func sameSignature(a, b manifestBinding) bool {
+	return a.Type == b.Type &&
+		a.Arity == b.Arity &&
+		a.Variadic == b.Variadic &&
+		slices.Equal(a.Params, b.Params) &&
+		slices.Equal(a.Results, b.Results)
+}
+
+// signatureString returns a human-readable signature
+// of the binding, e.g. "func(*net_http.Client, *net_http.Request) (*net_http.Response, error)".
+func signatureString(b manifestBinding) string {
+	params := slices.Clone(b.Params)
+	if b.Variadic && len(params) > 0 {
+		params[len(params)-1] = "..." + strings.TrimPrefix(params[len(params)-1], "[]")
+	}
+	res := b.Type + "(" + strings.Join(params, ", ") + ")"
+	switch len(b.Results) {
+	case 0:
+	case 1:
+		res += " " + b.Results[0]
+	default:
+		res += " (" + strings.Join(b.Results, ", ") + ")"
+	}
+	return res
+}
+
+// write prints the diff in a human-readable form.
+func (d manifestDiff) write(w io.Writer) {
+	if len(d) == 0 {
+		fmt.Fprintf(w, "no binding changes\n")
+		return
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(d)) {
+		pd := d[pkg]
+		fmt.Fprintf(w, "package %v:\n", pkg)
+		for _, b := range pd.removed {
+			fmt.Fprintf(w, "  - %v%v\n", b.Key, aliasSuffix(b))
+		}
+		for _, r := range pd.renamed {
+			fmt.Fprintf(w, "  ~ %v -> %v (%v)\n", r.old.Key, r.new.Key, r.new.GoSymbol)
+		}
+		for _, c := range pd.changed {
+			fmt.Fprintf(w, "  ! %v: %v -> %v\n", c.new.Key, signatureString(c.old), signatureString(c.new))
+		}
+		for _, b := range pd.added {
+			fmt.Fprintf(w, "  + %v%v\n", b.Key, aliasSuffix(b))
+		}
+	}
+}
+
+// aliasSuffix returns e.g. " (alias of Get)" if b is an alias.
+func aliasSuffix(b manifestBinding) string {
+	if b.aliasOf == "" {
+		return ""
+	}
+	return " (alias of " + b.aliasOf + ")"
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffManifests(t *testing.T) {
+	require := require.New(t)
+
+	fn := func(pkg, key, goSymbol string, params ...string) manifestBinding {
+		return manifestBinding{
+			Package:  pkg,
+			Name:     key,
+			Key:      key,
+			GoSymbol: goSymbol,
+			Type:     "func",
+			Arity:    len(params),
+			Params:   params,
+			Results:  []string{},
+		}
+	}
+
+	old := manifest{Bindings: []manifestBinding{
+		fn("a", "Same", "a.Same", "int"),
+		fn("a", "Removed", "a.Removed"),
+		fn("a", "OldName", "a.X"),
+		fn("a", "Changed", "a.Changed", "int"),
+		fn("b", "Same", "b.Same"),
+	}}
+	new := manifest{Bindings: []manifestBinding{
+		fn("a", "Same", "a.Same", "int"),
+		fn("a", "NewName", "a.X"),
+		fn("a", "Changed", "a.Changed", "int", "string"),
+		fn("a", "Added", "a.Added"),
+		fn("b", "Same", "b.Same"),
+	}}
+
+	diff := diffManifests(old, new)
+	require.Len(diff, 1)
+	require.Contains(diff, "a")
+	pd := diff["a"]
+	require.Equal([]manifestBinding{fn("a", "Added", "a.Added")}, pd.added)
+	require.Equal([]manifestBinding{fn("a", "Removed", "a.Removed")}, pd.removed)
+	require.Len(pd.renamed, 1)
+	require.Equal("OldName", pd.renamed[0].old.Key)
+	require.Equal("NewName", pd.renamed[0].new.Key)
+	require.Len(pd.changed, 1)
+	require.Equal("Changed", pd.changed[0].new.Key)
+	require.True(diff.breaking())
+
+	var b bytes.Buffer
+	diff.write(&b)
+	require.Equal(`package a:
+  - Removed
+  ~ OldName -> NewName (a.X)
+  ! Changed: func(int) -> func(int, string)
+  + Added
+`, b.String())
+
+	require.Empty(diffManifests(new, new))
+	require.False(diffManifests(new, new).breaking())
+
+	// Removed aliases are breaking changes
+	withAliases := func(b manifestBinding, aliases ...manifestAlias) manifestBinding {
+		b.Aliases = aliases
+		return b
+	}
+	old = manifest{Bindings: []manifestBinding{
+		withAliases(fn("a", "answer", "a.Answer"),
+			manifestAlias{Name: "Answer", Key: "Answer", Deprecated: true},
+			manifestAlias{Name: "ans", Key: "ans"}),
+	}}
+	new = manifest{Bindings: []manifestBinding{
+		withAliases(fn("a", "answer", "a.Answer"),
+			manifestAlias{Name: "ans", Key: "ans"},
+			manifestAlias{Name: "the-answer", Key: "the-answer"}),
+	}}
+	diff = diffManifests(old, new)
+	require.True(diff.breaking())
+	b.Reset()
+	diff.write(&b)
+	require.Equal(`package a:
+  - Answer (alias of answer)
+  + the-answer (alias of answer)
+`, b.String())
+}
+
+func TestManifestFromBuiltinsFile(t *testing.T) {
+	require := require.New(t)
+
+	src := "var builtins_net_http = make(map[string]*_env.VarBuiltin, 2)\n" +
+		"func init() {\n" +
+		"\tm := builtins_net_http\n" +
+		"\tm[\"Get\"] = mustBuiltin(x)\n" +
+		"\tm[\"Client//Do\"] = mustBuiltin(y)\n" +
+		"\tm[\"Get\\\\http\"] = mustBuiltin(z)\n" +
+		"}\n" +
+		"func init() {\n" +
+		"\tbuiltins[\"net/http\"] = builtins_net_http\n" +
+		"}\n"
+	m := manifestFromBuiltinsFile([]byte(src))
+	require.ElementsMatch([]manifestBinding{
+		{Package: "net/http", Name: "Get", Key: "Get"},
+		{Package: "net/http", Receiver: "Client", Name: "Do", Key: "Client//Do"},
+		{Package: "net/http", Name: `Get\http`, Key: `Get\http`},
+	}, m.Bindings)
+
+	// Lazily initialized builtins
+	src = "func init() {\n" +
+		"\taddBuiltins(\"net/http\", func(m map[string]*_env.VarBuiltin) {\n" +
+		"\t\tm[\"Get\"] = mustBuiltin(x)\n" +
+		"\t\tm[\"Client//Do\"] = mustBuiltin(y)\n" +
+		"\t})\n" +
+		"}\n" +
+		"func init() {\n" +
+		"\taddBuiltins(\"io\", func(m map[string]*_env.VarBuiltin) {\n" +
+		"\t\tm[\"EOF?\"] = mustBuiltin(z)\n" +
+		"\t})\n" +
+		"}\n"
+	require.ElementsMatch([]manifestBinding{
+		{Package: "net/http", Name: "Get", Key: "Get"},
+		{Package: "net/http", Receiver: "Client", Name: "Do", Key: "Client//Do"},
+		{Package: "io", Name: "EOF?", Key: "EOF?"},
+	}, manifestFromBuiltinsFile([]byte(src)).Bindings)
+
+	// Aliases
+	src = "func init() {\n" +
+		"\taddBuiltins(\"a\", func(m map[string]*_env.VarBuiltin) {\n" +
+		"\t\tm[\"answer\"] = mustBuiltin(x)\n" +
+		"\t\tm[\"Answer\"] = deprecatedAlias(m[\"answer\"], \"Answer\", \"answer\")\n" +
+		"\t\tm[\"ans\"] = m[\"answer\"]\n" +
+		"\t\tm[\"T//Get\"] = mustBuiltin(y)\n" +
+		"\t\tm[\"T//get\"] = m[\"T//Get\"]\n" +
+		"\t})\n" +
+		"}\n"
+	fromFile := manifestFromBuiltinsFile([]byte(src))
+	require.ElementsMatch([]manifestBinding{
+		{Package: "a", Name: "answer", Key: "answer", Aliases: []manifestAlias{
+			{Name: "Answer", Key: "Answer", Deprecated: true},
+			{Name: "ans", Key: "ans"},
+		}},
+		{Package: "a", Receiver: "T", Name: "Get", Key: "T//Get", Aliases: []manifestAlias{
+			{Name: "get", Key: "T//get"},
+		}},
+	}, fromFile.Bindings)
+	// Unchanged aliases aren't reported
+	require.Empty(diffManifests(fromFile, manifest{Bindings: []manifestBinding{
+		{Package: "a", Name: "answer", Key: "answer", GoSymbol: "a.Answer", Type: "func", Aliases: []manifestAlias{
+			{Name: "Answer", Key: "Answer", Deprecated: true},
+			{Name: "ans", Key: "ans"},
+		}},
+		{Package: "a", Receiver: "T", Name: "Get", Key: "T//Get", GoSymbol: "(a.T).Get", Type: "func", Aliases: []manifestAlias{
+			{Name: "get", Key: "T//get"},
+		}},
+	}}))
+
+	// Bindings without known signatures can only be added or removed
+	diff := diffManifests(m, manifest{Bindings: []manifestBinding{
+		{Package: "net/http", Name: "Get", Key: "Get", GoSymbol: "net/http.Get", Type: "func"},
+	}})
+	require.Len(diff, 1)
+	require.Len(diff["net/http"].removed, 2)
+	require.Empty(diff["net/http"].changed)
+}
+
+func TestLoadPreviousManifest(t *testing.T) {
+	require := require.New(t)
+
+	// The manifest is read from the output directory
+	dir := t.TempDir()
+	files := newOutputFiles(&config.Output{Dir: filepath.Join(dir, "bind")})
+	require.NoError(os.Mkdir(files.dir, 0777))
+	data, err := manifest{
+		Generator: "ryegen",
+		Version:   manifestVersion,
+		Bindings:  []manifestBinding{{Package: "a", Name: "Get", Key: "Get"}},
+	}.Marshal()
+	require.NoError(err)
+	require.NoError(os.WriteFile(files.manifest(), data, 0666))
+	m, from, err := loadPreviousManifest("", files.manifest(), files.builtins("linux_amd64"), nil)
+	require.NoError(err)
+	require.Equal(files.manifest(), from)
+	require.Len(m.Bindings, 1)
+
+	// Falling back to the builtins file
+	require.NoError(os.Remove(files.manifest()))
+	require.NoError(os.WriteFile(files.builtins("linux_amd64"), []byte("\taddBuiltins(\"a\", func(m map[string]*_env.VarBuiltin) {\n\t\tm[\"Get\"] = mustBuiltin(x)\n"), 0666))
+	m, from, err = loadPreviousManifest("", files.manifest(), files.builtins("linux_amd64"), nil)
+	require.NoError(err)
+	require.Equal(files.builtins("linux_amd64"), from)
+	require.Equal([]manifestBinding{{Package: "a", Name: "Get", Key: "Get"}}, m.Bindings)
+}
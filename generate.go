@@ -0,0 +1,583 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go/types"
+	"maps"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/converter"
+	"github.com/refaktor/ryegen/v2/converter/typeset"
+	"github.com/refaktor/ryegen/v2/loader"
+	"golang.org/x/tools/go/packages"
+)
+
+// targetGenerator generates the bindings of a target Go package
+// by Go package (see [pkgOutput]). The outputs of packages that
+// didn't change are taken from the cache, and only the other
+// packages are loaded and generated.
+//
+// The packages are visited in rounds (see [targetGenerator.round]).
+// A round that visits a package without output that isn't loaded
+// yet loads it for the next round, so a run usually takes two
+// rounds, and just one if everything is cached.
+type targetGenerator struct {
+	logger  *Logger
+	cfg     *config.Config
+	tc      targetConfig
+	basePkg string
+	// See [bindingSet.explain]
+	explain *regexp.Regexp
+	// Key of the inputs besides the packages (see [genInputsKey])
+	inputsKey [sha256.Size]byte
+	// Cache of the previous run; nil if outputs aren't reused
+	cache *genCache
+
+	scopes  []sourceScope
+	ruleIdx map[*config.Rule]int // index in cfg.AllRules()
+	// Packages whose cached output was generated
+	// with other native kind names
+	stale map[string]bool
+	// Packages loaded by the next round
+	toLoad []string
+
+	// Results of the last round
+	paths       []string              // visited packages, in order
+	generated   int                   // number of generated packages
+	outputs     map[string]*pkgOutput // by path
+	kindNames   map[string]string     // see [bindingSet.recvNames]
+	chunks      map[[sha256.Size]byte]converter.Chunk
+	refBindings []referenceBinding // of the generated packages
+	graph       *converter.Graph   // of the generated packages
+	importGraph map[string][]string
+}
+
+// newTargetGenerator returns a generator of the bindings of
+// tc. If cache is nil, all packages are generated.
+func newTargetGenerator(logger *Logger, cfg *config.Config, tc targetConfig, basePkg string, inputsKey [sha256.Size]byte, cache *genCache) (*targetGenerator, error) {
+	scopes, err := makeSourceScopes(tc.sources, func(patterns []string) ([]string, error) {
+		c := *tc.loaderCfg
+		c.PackagePatterns = patterns
+		return loader.ResolvePatterns(&c)
+	}, tc.deps.Roots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve source package patterns: %w", err)
+	}
+	g := &targetGenerator{
+		logger:    logger,
+		cfg:       cfg,
+		tc:        tc,
+		basePkg:   basePkg,
+		inputsKey: inputsKey,
+		cache:     cache,
+		scopes:    scopes,
+		ruleIdx:   map[*config.Rule]int{},
+		stale:     map[string]bool{},
+	}
+	for i, rule := range cfg.AllRules() {
+		g.ruleIdx[rule] = i
+	}
+	return g, nil
+}
+
+// canCachePackages reports whether the output of each
+// Go package can be cached separately, which isn't the case
+// if rules move bindings between packages.
+func canCachePackages(cfg *config.Config) bool {
+	for _, rule := range cfg.AllRules() {
+		if rule.Actions.SetPackage != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func shouldVisitPackage(path string) bool {
+	for elem := range strings.SplitSeq(path, "/") {
+		if elem == "internal" || elem == "cmd" {
+			return false
+		}
+	}
+	if strings.HasPrefix(path, "vendor/") {
+		// Ignore Go vendored std library modules.
+		// See https://cs.opensource.google/go/go/+/master:src/README.vendor.
+		// TODO: Figure out if this could break
+		// user-vendored modules.
+		return false
+	}
+	return true
+}
+
+// pkgKey returns the cache key of the output of the
+// package at path (see [pkgCacheKey]).
+func (g *targetGenerator) pkgKey(path string) (_ [sha256.Size]byte, ok bool) {
+	hash, ok := g.tc.deps.PackageHashes[path]
+	if !ok {
+		return [sha256.Size]byte{}, false
+	}
+	var rules []int
+	for _, rule := range rulesForPackage(g.cfg, g.scopes, path) {
+		rules = append(rules, g.ruleIdx[rule])
+	}
+	return pkgCacheKey(g.inputsKey, path, hash, rules), true
+}
+
+// cached returns the cached output of the package at
+// path, or nil if there is none.
+func (g *targetGenerator) cached(path string) *pkgOutput {
+	if g.cache == nil || g.stale[path] {
+		return nil
+	}
+	key, ok := g.pkgKey(path)
+	if !ok {
+		return nil
+	}
+	out := g.cache.Packages[key]
+	if out == nil {
+		return nil
+	}
+	for _, hash := range out.Chunks {
+		if _, ok := g.cache.Chunks[hash]; !ok {
+			return nil
+		}
+	}
+	return out
+}
+
+// run visits all packages of the target.
+func (g *targetGenerator) run() error {
+	for {
+		var pkgs []*packages.Package
+		if len(g.toLoad) > 0 {
+			c := *g.tc.loaderCfg
+			c.PackagePatterns = g.toLoad
+			var err error
+			pkgs, err = loader.Load(&c)
+			if err != nil {
+				return fmt.Errorf(`failed to load packages: %w
+re-running after "go mod tidy" might fix the error`, err)
+			}
+		}
+		need, err := g.round(pkgs)
+		if err != nil {
+			return err
+		}
+		if len(need) == 0 {
+			return nil
+		}
+		for _, path := range need {
+			if slices.Contains(g.toLoad, path) {
+				return fmt.Errorf("package %v wasn't loaded", path)
+			}
+		}
+		g.toLoad = append(g.toLoad, need...)
+		slices.Sort(g.toLoad)
+	}
+}
+
+// round visits the packages from the roots of the target.
+// Packages without (valid) cached output are generated
+// if they are among pkgs or their dependencies, and are
+// otherwise returned in need, in which case the round has
+// no results.
+func (g *targetGenerator) round(pkgs []*packages.Package) (need []string, err error) {
+	loaded := map[string]*packages.Package{}
+	docs := docIndex{}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		loaded[p.PkgPath] = p
+		docs.add(p.TypesInfo, p.Syntax)
+	})
+
+	qualifier := types.Qualifier(func(p *types.Package) string {
+		path := p.Path()
+		if path == g.basePkg {
+			return ""
+		}
+		return packagePathToImportName(path)
+	})
+	tset := typeset.New(qualifier)
+
+	bset := newBindingSet()
+	if g.explain != nil {
+		bset.explain = g.explain
+		bset.explainW = os.Stdout
+	}
+
+	// A generated package, and the range of its bindings in bset
+	type generatedPkg struct {
+		path       string
+		out        *pkgOutput
+		start, end int
+	}
+	var generated []generatedPkg
+	var paths []string
+	outputs := map[string]*pkgOutput{}
+	importGraph := map[string][]string{} // pkg path to imported paths; for debugging
+
+	// The bindings of the packages that are going to be
+	// generated are made in parallel. Adding them to bset
+	// depends on the order, so that is done by visit.
+	pkgBindings := map[string]func() []binding{}
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	startBindings := func(path string) {
+		p := loaded[path]
+		if _, ok := pkgBindings[path]; ok || p == nil || !shouldVisitPackage(path) || g.cached(path) != nil {
+			return
+		}
+		res := make(chan []binding, 1)
+		go func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res <- makePkgBindings(tset, p.TypesInfo, p.Syntax, docs)
+		}()
+		pkgBindings[path] = sync.OnceValue(func() []binding { return <-res })
+	}
+
+	// generate adds the bindings of the loaded package p to bset.
+	generate := func(p *packages.Package) (*pkgOutput, error) {
+		start := len(bset.bindings)
+		prevMatches := maps.Clone(bset.ruleMatches)
+		prevConflicts := len(bset.conflictResolutions)
+		prevRecvNames := maps.Clone(bset.recvNames)
+
+		bfs, err := bset.addWithRules(g.cfg, rulesForPackage(g.cfg, g.scopes, p.PkgPath), pkgBindings[p.PkgPath]())
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply binding rules: %w", err)
+		}
+
+		// We only want to make bindings for the imports actually
+		// used by at least one of the bindings in this package
+		// (after applying binding rules).
+		usedImports := map[string]bool{}
+		for _, bf := range bfs {
+			// In this case, we're collecting all imports required
+			// to represent the required converter func. I'm pretty
+			// sure - but not 100% - that this should correspond
+			// to collecting all API dependencies.
+			for _, pkg := range collectImports(bf.requiredConverter) {
+				usedImports[pkg.Path()] = true
+			}
+		}
+
+		out := &pkgOutput{
+			RecvNames:           map[string]string{},
+			RuleMatches:         map[int]int{},
+			ConflictResolutions: slices.Clone(bset.conflictResolutions[prevConflicts:]),
+		}
+		for _, imp := range p.Imports {
+			if usedImports[imp.PkgPath] {
+				out.Visit = append(out.Visit, imp.PkgPath)
+			}
+		}
+		slices.Sort(out.Visit)
+		for rule, n := range bset.ruleMatches {
+			if n > prevMatches[rule] {
+				out.RuleMatches[g.ruleIdx[rule]] = n - prevMatches[rule]
+			}
+		}
+		for initial, name := range bset.recvNames {
+			if prev, ok := prevRecvNames[initial]; !ok || prev != name {
+				out.RecvNames[initial] = name
+			}
+		}
+		for _, props := range bset.initialProps[start:] {
+			if props.recv != "" {
+				out.Receivers = append(out.Receivers, props.recv)
+			}
+		}
+		out.Receivers = slices.Compact(slices.Sorted(slices.Values(out.Receivers)))
+		generated = append(generated, generatedPkg{path: p.PkgPath, out: out, start: start, end: len(bset.bindings)})
+		return out, nil
+	}
+
+	seen := map[string]bool{}
+	var visit func(path string) error
+	visit = func(path string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		if !shouldVisitPackage(path) {
+			return nil
+		}
+
+		out := g.cached(path)
+		if out == nil {
+			p := loaded[path]
+			if p == nil {
+				need = append(need, path)
+				return nil
+			}
+			startBindings(path)
+			var err error
+			if out, err = generate(p); err != nil {
+				return err
+			}
+		}
+		paths = append(paths, path)
+		outputs[path] = out
+
+		var imports []string
+		for _, imp := range out.Visit {
+			if !seen[imp] {
+				imports = append(imports, imp)
+			}
+		}
+		importGraph[path] = imports
+		for _, imp := range imports {
+			startBindings(imp)
+		}
+		for _, imp := range imports {
+			if err := visit(imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	roots := slices.Clone(g.tc.deps.Roots)
+	slices.SortFunc(roots, func(a, b *packages.Package) int { return cmp.Compare(a.PkgPath, b.PkgPath) })
+	for _, p := range roots {
+		startBindings(p.PkgPath)
+	}
+	for _, p := range roots {
+		if err := visit(p.PkgPath); err != nil {
+			return nil, err
+		}
+	}
+	if len(need) > 0 {
+		return need, nil
+	}
+
+	kindNames, err := mergeRecvNames(paths, outputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		for kind, name := range outputs[path].KindNames {
+			if cmp.Or(kindNames[kind], kind) != name {
+				g.stale[path] = true
+				need = append(need, path)
+				break
+			}
+		}
+	}
+	if len(need) > 0 {
+		return need, nil
+	}
+
+	cs := converter.NewConverterSet(tset, g.basePkg)
+	cs.SetNativeKindNames(kindNames)
+	cs.SetReflectTypes(g.cfg.ReflectTypes())
+	convNames := map[*pkgOutput][]string{} // of the bindings of each generated package
+	for _, gp := range generated {
+		for _, fn := range bset.bindings[gp.start:gp.end] {
+			if fn.props.exclude {
+				continue
+			}
+			convNames[gp.out] = append(convNames[gp.out], cs.Add(fn.requiredConverter, converter.ToRye, fn.ryePkg()+"::"+fn.key()))
+		}
+	}
+	_, graph, err := cs.Chunks()
+	if err != nil {
+		var convErr *converter.ConverterError
+		if !errors.As(err, &convErr) {
+			return nil, fmt.Errorf("failed to generate converter code: %w", err)
+		}
+		g.logger.Log(WARN, "some converters had errors:\n%v", convErr.String())
+	}
+
+	withDocs := g.cfg.Docs == nil || *g.cfg.Docs
+	chunks := map[[sha256.Size]byte]converter.Chunk{}
+	var refBindings []referenceBinding
+	for _, gp := range generated {
+		out := gp.out
+		bindings := slices.DeleteFunc(slices.Clone(bset.bindings[gp.start:gp.end]), func(bf binding) bool { return bf.props.exclude })
+		var refs []referenceBinding
+		var nodes []converter.GraphNode
+		registered := map[string]bool{}
+		for i, fn := range bindings {
+			pkg := fn.ryePkg()
+			if !registered[pkg] {
+				registered[pkg] = true
+				out.Builtins = append(out.Builtins, cachedBuiltinsEntry{Pkg: pkg})
+			}
+			if !graph.Contains(fn.requiredConverter, converter.ToRye) {
+				refs = append(refs, referenceBinding{
+					binding: fn,
+					pkg:     pkg,
+					err:     graph.Error(fn.requiredConverter, converter.ToRye),
+				})
+				continue
+			}
+			refs = append(refs, referenceBinding{binding: fn, pkg: pkg})
+			nodes = append(nodes, converter.GraphNode{Type: fn.requiredConverter, Dir: converter.ToRye})
+
+			// Aliases refer to the builtin, so they
+			// are part of the same entry.
+			code := fmt.Sprintf(`m[%q] = %v`+"\n", fn.key(), fn.binding(convNames[out][i], withDocs))
+			for _, alias := range fn.props.aliases {
+				code += fn.aliasCode(alias) + "\n"
+			}
+			var imports []string
+			for _, imp := range fn.funcCodeImports {
+				imports = append(imports, imp.Path())
+			}
+			out.Builtins = append(out.Builtins, cachedBuiltinsEntry{Pkg: pkg, Key: fn.key(), Code: code, Imports: imports})
+		}
+		pkgChunks, kinds := cs.ChunksFor(graph, nodes)
+		for _, c := range pkgChunks {
+			hash := chunkHash(c)
+			chunks[hash] = c
+			out.Chunks = append(out.Chunks, hash)
+		}
+		out.KindNames = map[string]string{}
+		for _, kind := range kinds {
+			out.KindNames[kind] = cmp.Or(kindNames[kind], kind)
+		}
+		out.Manifest = manifestBindings(refs, tset)
+		refBindings = append(refBindings, refs...)
+	}
+
+	g.paths = paths
+	g.generated = len(generated)
+	g.outputs = outputs
+	g.kindNames = kindNames
+	g.chunks = chunks
+	g.refBindings = refBindings
+	g.graph = graph
+	g.importGraph = importGraph
+	return nil, nil
+}
+
+// mergeRecvNames returns the receiver names set by the rules
+// of all packages (see [bindingSet.recvNames]), checking that
+// they don't conflict like [bindingSet.renameRecv].
+func mergeRecvNames(paths []string, outputs map[string]*pkgOutput) (map[string]string, error) {
+	res := map[string]string{}
+	for _, path := range paths {
+		for initial, name := range outputs[path].RecvNames {
+			res[initial] = name
+		}
+	}
+	byName := map[string]string{}
+	for _, initial := range slices.Sorted(maps.Keys(res)) {
+		name := res[initial]
+		if other, ok := byName[name]; ok {
+			return nil, fmt.Errorf("to-casing: renaming receiver %v to %v would cause naming conflict with receiver %v", initial, name, other)
+		}
+		byName[name] = initial
+	}
+	for _, path := range paths {
+		for _, recv := range outputs[path].Receivers {
+			if _, renamed := res[recv]; renamed {
+				continue
+			}
+			if initial, ok := byName[recv]; ok && initial != recv {
+				return nil, fmt.Errorf("to-casing: renaming receiver %v to %v would cause naming conflict with receiver %v", initial, recv, recv)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ruleMatches returns the number of bindings
+// selected by each rule.
+func (g *targetGenerator) ruleMatches() map[*config.Rule]int {
+	rules := g.cfg.AllRules()
+	res := map[*config.Rule]int{}
+	for _, path := range g.paths {
+		for i, n := range g.outputs[path].RuleMatches {
+			res[rules[i]] += n
+		}
+	}
+	return res
+}
+
+// conflictResolutions returns the naming conflicts
+// resolved in all packages.
+func (g *targetGenerator) conflictResolutions() []string {
+	var res []string
+	for _, path := range g.paths {
+		res = append(res, g.outputs[path].ConflictResolutions...)
+	}
+	return res
+}
+
+// chunk returns the converter chunk with the given hash.
+func (g *targetGenerator) chunk(hash [sha256.Size]byte) converter.Chunk {
+	if c, ok := g.chunks[hash]; ok {
+		return c
+	}
+	return g.cache.Chunks[hash].chunk()
+}
+
+// code returns the builtins and converter chunks of the
+// target, merged from the outputs of all packages.
+func (g *targetGenerator) code() (builtins []builtinsEntry, convs []converter.Chunk) {
+	seenBuiltins := map[[2]string]bool{}
+	seenChunks := map[[sha256.Size]byte]bool{}
+	for _, path := range g.paths {
+		out := g.outputs[path]
+		for _, e := range out.Builtins {
+			if !seenBuiltins[[2]string{e.Pkg, e.Key}] {
+				seenBuiltins[[2]string{e.Pkg, e.Key}] = true
+				builtins = append(builtins, e.entry())
+			}
+		}
+		for _, hash := range out.Chunks {
+			if !seenChunks[hash] {
+				seenChunks[hash] = true
+				convs = append(convs, g.chunk(hash))
+			}
+		}
+	}
+	slices.SortFunc(builtins, func(a, b builtinsEntry) int {
+		return cmp.Or(cmp.Compare(a.pkg, b.pkg), cmp.Compare(a.key, b.key))
+	})
+	convs = append(convs, converter.NativeKindNamesChunk(g.kindNames))
+	converter.SortChunks(convs)
+	return builtins, convs
+}
+
+// manifest returns the manifest of the target.
+func (g *targetGenerator) manifest(target string) manifest {
+	var bindings []manifestBinding
+	var nativeKinds []string
+	for _, path := range g.paths {
+		out := g.outputs[path]
+		bindings = append(bindings, out.Manifest...)
+		nativeKinds = slices.AppendSeq(nativeKinds, maps.Values(out.KindNames))
+	}
+	slices.Sort(nativeKinds)
+	return makeManifest(target, bindings, slices.Compact(nativeKinds))
+}
+
+// store adds the outputs of all packages to cache.
+func (g *targetGenerator) store(cache *genCache) {
+	for _, path := range g.paths {
+		key, ok := g.pkgKey(path)
+		if !ok {
+			continue
+		}
+		out := g.outputs[path]
+		cache.Packages[key] = out
+		for _, hash := range out.Chunks {
+			if _, ok := cache.Chunks[hash]; !ok {
+				if c, ok := g.chunks[hash]; ok {
+					cache.Chunks[hash] = newCachedChunk(c)
+				} else {
+					cache.Chunks[hash] = g.cache.Chunks[hash]
+				}
+			}
+		}
+	}
+}
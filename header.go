@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/refaktor/ryegen/v2/loader"
+)
+
+// ryegenVersion returns the module version of the running
+// Ryegen executable. Development builds are identified by
+// their VCS revision, if available.
+func ryegenVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	if v != "" && v != "(devel)" {
+		return v
+	}
+	var rev, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			if s.Value == "true" {
+				modified = "+dirty"
+			}
+		}
+	}
+	if rev == "" {
+		return "(devel)"
+	}
+	return "(devel) " + rev + modified
+}
+
+// generatedFromComment returns the comment following the
+// "Code generated" line of the builtins and converters files,
+// which records what they were generated from, e.g.:
+//
+//	// Ryegen version: v2.1.0
+//	// Config hash: 9f86d081884c7d65...
+//	// Go version: go1.24.1
+//	// Source modules:
+//	//	fyne.io/fyne/v2@v2.6.1
+//
+// deps are the sources of all targets the file is for.
+func generatedFromComment(version string, cfgHash [sha256.Size]byte, deps []*loader.Sources) string {
+	goVersions := map[string]bool{}
+	modules := map[string]bool{}
+	for _, d := range deps {
+		goVersions[d.GoVersion] = true
+		for _, m := range d.Modules {
+			modules[m] = true
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Ryegen version: %v\n", version)
+	fmt.Fprintf(&b, "// Config hash: %x\n", cfgHash)
+	fmt.Fprintf(&b, "// Go version: %v\n", strings.Join(slices.Sorted(maps.Keys(goVersions)), ", "))
+	if len(modules) > 0 {
+		b.WriteString("// Source modules:\n")
+		for _, m := range slices.Sorted(maps.Keys(modules)) {
+			fmt.Fprintf(&b, "//\t%v\n", m)
+		}
+	}
+	return b.String()
+}
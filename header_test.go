@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/loader"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedFromComment(t *testing.T) {
+	deps := []*loader.Sources{
+		{GoVersion: "go1.24.1", Modules: []string{"example.com/a@v1.0.0", "example.com/main"}},
+		{GoVersion: "go1.24.1", Modules: []string{"example.com/a@v1.0.0", "example.com/b => example.com/c@v0.1.0"}},
+	}
+	require.Equal(t, `// Ryegen version: v2.1.0
+// Config hash: 0100000000000000000000000000000000000000000000000000000000000000
+// Go version: go1.24.1
+// Source modules:
+//	example.com/a@v1.0.0
+//	example.com/b => example.com/c@v0.1.0
+//	example.com/main
+`, generatedFromComment("v2.1.0", [32]byte{1}, deps))
+
+	require.Equal(t, `// Ryegen version: (devel)
+// Config hash: 0000000000000000000000000000000000000000000000000000000000000000
+// Go version: go1.24.1
+`, generatedFromComment("(devel)", [32]byte{}, []*loader.Sources{{GoVersion: "go1.24.1"}}), "std only")
+}
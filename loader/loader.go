@@ -1,17 +1,25 @@
 package loader
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
+	"maps"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/refaktor/ryegen/v2/pkgutils"
 	"github.com/refaktor/ryegen/v2/preprocessor"
+	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -137,3 +145,194 @@ func Load(c *Config) ([]*packages.Package, error) {
 		},
 	})
 }
+
+// DirPackagePath returns the import path of the package in dir,
+// which doesn't have to contain any Go files (yet). dir must be
+// inside the main module.
+func DirPackagePath(c *Config, dir string) (string, error) {
+	cmd := exec.Command("go", "env", "GOMOD")
+	cmd.Env = append(os.Environ(), c.Env...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOMOD: %w", err)
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return "", errors.New("not inside a Go module")
+	}
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return "", err
+	}
+	modPath := modfile.ModulePath(data)
+	if modPath == "" {
+		return "", errors.New(gomod + ": no module path")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(filepath.Dir(gomod), absDir)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%v is outside of module %v", dir, modPath)
+	}
+	return path.Join(modPath, rel), nil
+}
+
+// Sources describes everything the result of [Load] depends on.
+type Sources struct {
+	GoVersion string
+	// Modules of all loaded packages, sorted. Each one is
+	// "path@version", "path => replacement@version", or just
+	// the path for modules without a version (e.g. the main
+	// module).
+	Modules []string
+	// Hash of the Go version and environment, the versions of
+	// the modules, and the file contents of packages that aren't
+	// part of a versioned module
+	Hash [sha256.Size]byte
+	// Hash of everything type-checking the packages depends on:
+	// the same as Hash, but instead of the build flags, it
+	// includes the files they select. Configs that only differ
+	// in build tags that select the same files have the same
+	// Packages hash, so they load the same packages.
+	Packages [sha256.Size]byte
+	// Hash of each loaded package by path: like Packages, but
+	// only of the package and the packages it imports
+	// (directly or indirectly)
+	PackageHashes map[string][sha256.Size]byte
+	// The root packages, with their imports, but without
+	// syntax or types
+	Roots []*packages.Package
+}
+
+// GoVersion returns the version of the go command used for
+// loading, as reported by "go env GOVERSION", e.g. "go1.24.1".
+func GoVersion() (string, error) {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ResolveSources returns the [Sources] of c. It doesn't
+// type-check any packages, so it's much faster than [Load].
+func ResolveSources(c *Config) (*Sources, error) {
+	res := &Sources{}
+
+	cmd := exec.Command("go", "env", "GOVERSION", "GOFLAGS", "GOEXPERIMENT", "GOOS", "GOARCH", "CGO_ENABLED")
+	cmd.Env = append(os.Environ(), c.Env...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env: %w", err)
+	}
+	lines := strings.SplitAfter(string(out), "\n")
+	if len(lines) < 6 {
+		return nil, fmt.Errorf("go env: unexpected output %q", out)
+	}
+	goEnv, platform := strings.Join(lines[:3], ""), strings.Join(lines[3:6], "")
+	res.GoVersion = strings.TrimSuffix(lines[0], "\n")
+
+	pkgs, err := loadPackagesStep(c, &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedModule | packages.NeedImports | packages.NeedDeps,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h, ph := sha256.New(), sha256.New()
+	both := io.MultiWriter(h, ph)
+	fmt.Fprintf(h, "%q %q %q\n", goEnv, c.Env, c.BuildFlags)
+	fmt.Fprintf(ph, "%q %q\n", goEnv, platform)
+	var all []*packages.Package
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		all = append(all, p)
+	})
+	slices.SortFunc(all, func(a, b *packages.Package) int {
+		return strings.Compare(a.PkgPath, b.PkgPath)
+	})
+	modules := map[string]bool{}
+	// Hashes of the packages without their imports
+	ownHashes := map[string][sha256.Size]byte{}
+	for _, p := range all {
+		own := sha256.New()
+		fmt.Fprintf(own, "%q %q\n", goEnv, platform)
+		w := io.MultiWriter(both, own)
+		fmt.Fprintf(w, "%v\n", p.PkgPath)
+		for _, name := range slices.Concat(p.GoFiles, p.OtherFiles) {
+			fmt.Fprintf(io.MultiWriter(ph, own), "%v\n", filepath.Base(name))
+		}
+		if err := writePackageSources(w, p, modules); err != nil {
+			return nil, err
+		}
+		ownHashes[p.PkgPath] = [sha256.Size]byte(own.Sum(nil))
+	}
+	ph.Sum(res.Packages[:0])
+
+	res.PackageHashes = map[string][sha256.Size]byte{}
+	var pkgHash func(p *packages.Package) [sha256.Size]byte
+	pkgHash = func(p *packages.Package) [sha256.Size]byte {
+		if hash, ok := res.PackageHashes[p.PkgPath]; ok {
+			return hash
+		}
+		own := ownHashes[p.PkgPath]
+		h := sha256.New()
+		h.Write(own[:])
+		for _, path := range slices.Sorted(maps.Keys(p.Imports)) {
+			imp := pkgHash(p.Imports[path])
+			h.Write(imp[:])
+		}
+		hash := [sha256.Size]byte(h.Sum(nil))
+		res.PackageHashes[p.PkgPath] = hash
+		return hash
+	}
+	for _, p := range all {
+		pkgHash(p)
+	}
+	res.Roots = pkgs
+	h.Sum(res.Hash[:0])
+	res.Modules = slices.Sorted(maps.Keys(modules))
+	return res, nil
+}
+
+// writePackageSources writes what the package p depends on,
+// besides the Go version, to w: the version of its module, or
+// the contents of its files if the module has no version. The
+// modules are added to modules (see [Sources.Modules]).
+func writePackageSources(w io.Writer, p *packages.Package, modules map[string]bool) error {
+	if p.Module == nil {
+		// Standard library, determined by the Go version
+		return nil
+	}
+	mod := p.Module
+	if mod.Replace != nil {
+		mod = mod.Replace
+	}
+	if mod.Version != "" {
+		fmt.Fprintf(w, "%v@%v\n", mod.Path, mod.Version)
+		if mod.Path != p.Module.Path {
+			modules[p.Module.Path+" => "+mod.Path+"@"+mod.Version] = true
+		} else {
+			modules[mod.Path+"@"+mod.Version] = true
+		}
+		return nil
+	}
+	// Local replacements are identified by the
+	// original path, which doesn't depend on
+	// where the module is checked out
+	modules[p.Module.Path] = true
+	for _, name := range slices.Concat(p.GoFiles, p.OtherFiles) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%v %v\n", filepath.Base(name), len(data))
+		w.Write(data)
+	}
+	return nil
+}
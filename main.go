@@ -3,7 +3,7 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"cmp"
+	"crypto/sha256"
 	"errors"
 	"flag"
 	"fmt"
@@ -21,13 +21,10 @@ import (
 	"strings"
 	"time"
 
-	"dario.cat/mergo"
 	"github.com/refaktor/ryegen/v2/config"
 	"github.com/refaktor/ryegen/v2/converter"
-	"github.com/refaktor/ryegen/v2/converter/typeset"
 	"github.com/refaktor/ryegen/v2/digraphutils"
 	"github.com/refaktor/ryegen/v2/loader"
-	"golang.org/x/tools/go/packages"
 )
 
 func isEnvTrue(name string) bool {
@@ -144,10 +141,8 @@ func handlePrintTime(logger *Logger) (stop func()) {
 	}
 }
 
-func isFileGeneratedByRyegen(path string) (bool, error) {
-	name := filepath.Base(path)
-	if !strings.HasPrefix(name, "ryegen_") ||
-		!strings.HasSuffix(name, ".gen.go") {
+func isFileGeneratedByRyegen(path string, files outputFiles) (bool, error) {
+	if !files.isGeneratedName(filepath.Base(path)) {
 		return false, nil
 	}
 
@@ -177,6 +172,36 @@ func isFileGeneratedByRyegen(path string) (bool, error) {
 	return true, nil
 }
 
+// outputNeutralFlags are the flags that don't change the
+// generated Go files, mapped to whether they take a value.
+var outputNeutralFlags = map[string]bool{
+	"check":    false,
+	"q":        false,
+	"v":        false,
+	"no-cache": false,
+	"docs":     true,
+	"explain":  true,
+}
+
+// headerArgs returns the args recorded in the header of the
+// generated files, which leaves out the output-neutral flags
+// (see outputNeutralFlags) and their values.
+func headerArgs(args []string) []string {
+	var res []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if takesValue, ok := outputNeutralFlags[name]; ok && strings.HasPrefix(arg, "-") {
+			if takesValue && !hasValue {
+				i++
+			}
+			continue
+		}
+		res = append(res, arg)
+	}
+	return res
+}
+
 func boolToBinStr(b bool) string {
 	if b {
 		return "1"
@@ -206,21 +231,86 @@ func (v TagsValue) Set(s string) error {
 	return nil
 }
 
+// ruleUsage counts how often rules matched in all targets.
+// A rule may only match on some targets, so unmatched rules
+// are reported after generating all of them.
+type ruleUsage struct {
+	rules   []*config.Rule // rules active in any target, in order
+	matches map[*config.Rule]int
+}
+
+// add adds the matches of a target's active rules.
+func (u *ruleUsage) add(active []*config.Rule, matches map[*config.Rule]int) {
+	if u.matches == nil {
+		u.matches = map[*config.Rule]int{}
+	}
+	for _, rule := range active {
+		if _, ok := u.matches[rule]; !ok {
+			u.rules = append(u.rules, rule)
+		}
+		u.matches[rule] += matches[rule]
+	}
+}
+
+// warnUnmatched warns about each rule that never matched.
+func (u *ruleUsage) warnUnmatched(logger *Logger) {
+	for _, rule := range u.rules {
+		if u.matches[rule] == 0 {
+			logger.Log(WARN, "%v: rule never matched any binding", rule.Pos)
+		}
+	}
+}
+
 // e.g. github.com/someone/somerepo => github_com_someone_somerepo
 func packagePathToImportName(path string) string {
 	return strings.NewReplacer("/", "_", ".", "_", "-", "_").Replace(path)
 }
 
 func main() {
-	var optClean = flag.Bool("clean", false, "delete Go files generated by Ryegen (pwd by default, or you can specify the directories as args)")
+	var optClean = flag.Bool("clean", false, "delete files generated by Ryegen (pwd and the output directory by default, or you can specify the directories as args)")
 	var optQuiet = flag.Bool("q", false, "quiet: hide warnings")
 	var optVerbose = flag.Bool("v", false, "verbose output")
 	var optGOOS = flag.String("goos", runtime.GOOS, "target operating system")
 	var optGOARCH = flag.String("goarch", runtime.GOARCH, "target CPU architecture")
+	var optDocs = flag.String("docs", "", "also write a Markdown API reference of the generated bindings to the given directory")
+	var optAllTargets = flag.Bool("all-targets", false, "generate bindings for every [[target]] in ryegen.toml that sets goos and goarch (instead of -goos, -goarch and -tags)")
+	var optDedup = flag.Bool("dedup", false, "with -all-targets: write code that is identical for all targets into shared files and only the rest into per-target files")
+	var optExplain = flag.String("explain", "", "print the rules applied to each binding whose Go symbol or Rye name matches the given regex")
+	var optOutDir = flag.String("out-dir", "", "directory to write the generated files to (overrides output.dir)")
+	var optOutPackage = flag.String("out-package", "", "Go package name of the generated files (overrides output.package)")
+	var optOutPrefix = flag.String("out-prefix", "", "prefix of the generated file names (overrides output.prefix)")
+	var optNoCache = flag.Bool("no-cache", false, "always generate the bindings, even if the inputs are unchanged since the last run")
+	var optDepsFile = flag.String("deps-file", "", "name of the generated file importing the sources (overrides output.deps-file)")
+	var optCheck = flag.Bool("check", false, "generate the bindings in memory and exit with an error if the generated files on disk differ (writes nothing)")
 	var optTags []string
 	flag.Var(TagsValue{V: &optTags}, "tags", "additional target build tags (separated by ,)")
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage:\n")
+		fmt.Fprintf(w, "  ryegen [flags]              generate bindings\n")
+		fmt.Fprintf(w, "  ryegen [flags] diff [file]  compare bindings against a previous manifest (default: %v in the output directory)\n", manifestFileName)
+		fmt.Fprintf(w, "  ryegen -clean [dirs]        delete generated files\n")
+		fmt.Fprintf(w, "Flags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	// In diff mode, bindings are generated in memory and
+	// compared against the previous generation result.
+	diffMode := flag.Arg(0) == "diff"
+	if flag.NArg() > 0 && !diffMode && !*optClean {
+		flag.Usage()
+		os.Exit(2)
+	}
+	// Nothing is written to disk in diff and check mode.
+	dryRun := diffMode || *optCheck
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	logger := &Logger{
 		Writer:   os.Stdout,
 		Prefix:   "Ryegen",
@@ -236,29 +326,71 @@ func main() {
 	defer handleEnvProfile(logger)()
 	defer handlePrintTime(logger)()
 
+	cfg, err := config.Load("ryegen.toml")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && *optClean {
+			// Clean up using the default output settings
+			cfg = &config.Config{}
+		} else if errors.Is(err, os.ErrNotExist) {
+			logger.Log(FATAL, "ryegen.toml not found")
+		} else if cfgErr := (&config.Error{}); errors.As(err, &cfgErr) {
+			logger.Log(FATAL, "unable to load ryegen.toml: %v", cfgErr.String())
+		} else {
+			logger.Log(FATAL, "unable to load ryegen.toml: %v", err)
+		}
+	}
+
+	if *optOutDir != "" {
+		cfg.Output.Dir = *optOutDir
+	}
+	if *optOutPackage != "" {
+		cfg.Output.Package = *optOutPackage
+	}
+	if *optOutPrefix != "" {
+		cfg.Output.Prefix = *optOutPrefix
+	}
+	if *optDepsFile != "" {
+		cfg.Output.DepsFile = *optDepsFile
+	}
+	if err := cfg.Output.Check(); err != nil {
+		logger.Log(FATAL, "output: %v", err)
+	}
+	files := newOutputFiles(&cfg.Output)
+
 	if *optClean {
 		dirs := flag.Args()
 		if len(dirs) == 0 {
 			dirs = []string{"."}
+			if filepath.Clean(files.dir) != "." {
+				dirs = append(dirs, files.dir)
+			}
 		}
-		var files []string
+		var toDelete []string
 		for _, dir := range dirs {
 			ents, err := os.ReadDir(dir)
 			if err != nil {
+				if errors.Is(err, os.ErrNotExist) && len(flag.Args()) == 0 {
+					continue
+				}
 				logger.Log(FATAL, "failed to read directory %v: %v", dir, err)
 			}
 			for _, ent := range ents {
 				path := filepath.Join(dir, ent.Name())
-				generated, err := isFileGeneratedByRyegen(path)
+				var generated bool
+				if ent.Name() == manifestFileName {
+					generated, err = isManifestGeneratedByRyegen(path)
+				} else {
+					generated, err = isFileGeneratedByRyegen(path, files)
+				}
 				if err != nil {
 					logger.Log(FATAL, "failed to read file %v: %v", dir, err)
 				}
 				if generated {
-					files = append(files, path)
+					toDelete = append(toDelete, path)
 				}
 			}
 		}
-		for _, f := range files {
+		for _, f := range toDelete {
 			if err := os.Remove(f); err != nil {
 				logger.Log(FATAL, "failed to delete file %v: %v", f, err)
 			}
@@ -266,321 +398,465 @@ func main() {
 		return
 	}
 
-	codeGeneratedLine := func(withArgs bool) string {
-		var args string
-		if withArgs && len(os.Args) > 1 {
-			args = " " + strings.Join(os.Args[1:], " ")
-		}
-		return fmt.Sprintf("// Code generated by ryegen%v; DO NOT EDIT.\n", args)
-	}
+	// Files written by this run, and their contents if
+	// they are going to be cached (see [genCache])
+	written := map[string]bool{}
+	var cacheFiles map[string][]byte
 
-	cfg, err := config.Load("ryegen.toml")
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			logger.Log(FATAL, "ryegen.toml not found")
-		} else if cfgErr := (&config.Error{}); errors.As(err, &cfgErr) {
-			logger.Log(FATAL, "unable to load ryegen.toml: %v", cfgErr.String())
-		} else {
-			logger.Log(FATAL, "unable to load ryegen.toml: %v", err)
-		}
+	var check *fileCheck
+	if *optCheck {
+		check = newFileCheck()
 	}
 
-	loaderCfg := &loader.Config{}
-
-	for _, src := range cfg.Sources {
-		loaderCfg.PackagePatterns = append(loaderCfg.PackagePatterns,
-			src.Packages...)
+	// writeFile writes a generated file (nothing is written
+	// to disk in diff mode, and in check mode, it is compared
+	// against the file on disk instead).
+	writeFile := func(name string, data []byte) error {
+		written[name] = true
+		if cacheFiles != nil {
+			cacheFiles[name] = data
+		}
+		if check != nil {
+			return check.compare(name, data)
+		}
+		if diffMode {
+			return nil
+		}
+		if old, err := os.ReadFile(name); err == nil && bytes.Equal(old, data) {
+			// Unchanged files aren't touched, so editors
+			// and file watchers don't reload them.
+			return nil
+		}
+		return os.WriteFile(name, data, 0666)
 	}
 
-	target := config.Target{
-		CGoEnabled: new(bool),
-	}
-	var targetName string
-	{
-		targetName = strings.Join(
-			append([]string{*optGOOS, *optGOARCH}, optTags...),
-			"_")
-
-		// TODO: Add unix tag if GOOS is unix
-		var tags []string
-		tags = append(tags, *optGOOS, *optGOARCH)
-		tags = append(tags, optTags...)
-		if !slices.Contains(tags, "gc") && !slices.Contains(tags, "gccgo") {
-			tags = append(tags, runtime.Compiler)
-		}
-
-		for _, tgt := range cfg.Targets {
-			if tgt.Select == nil || tgt.Select.Eval(func(tag string) bool {
-				return slices.Contains(tags, tag)
-			}) {
-				if err := mergo.Merge(&target, &tgt); err != nil {
-					logger.Log(FATAL, "unable to merge matching targets: %v", err) // this should really not happen, ever.
-				}
-			}
+	// removeFile deletes a stale generated file.
+	removeFile := func(name string) error {
+		if check != nil {
+			check.stale(name)
+			return nil
 		}
+		if diffMode {
+			return nil
+		}
+		return os.Remove(name)
 	}
 
-	loaderCfg.Env = append(loaderCfg.Env,
-		"GOOS="+*optGOOS,
-		"GOARCH="+*optGOARCH,
-		"CGO_ENABLED="+boolToBinStr(*target.CGoEnabled),
-	)
-	loaderCfg.BuildFlags = append(loaderCfg.BuildFlags,
-		"-tags="+strings.Join(optTags, ","),
-	)
+	var codeGeneratedArgs string
+	if a := headerArgs(os.Args[1:]); len(a) > 0 {
+		codeGeneratedArgs = " " + strings.Join(a, " ")
+	}
+	codeGeneratedLine := fmt.Sprintf("// Code generated by ryegen%v; DO NOT EDIT.\n", codeGeneratedArgs)
+	version := ryegenVersion()
 
-	{
-		pkgs, err := loader.ResolvePatterns(loaderCfg)
-		if err != nil {
-			logger.Log(FATAL, "failed to resolve package patterns: %v", err)
+	if !dryRun {
+		if err := os.MkdirAll(files.dir, 0777); err != nil {
+			logger.Log(FATAL, "creating output directory: %v", err)
 		}
+	}
+	pkgName, _ := cfg.Output.PackageName() // checked above
+	packageLine := "package " + pkgName + "\n\n"
 
-		var out bytes.Buffer
-		out.WriteString(codeGeneratedLine(false))
-		out.WriteString("package main\n\n")
-		out.WriteString("import (\n")
-		for _, pkg := range pkgs {
-			fmt.Fprintf(&out, "\t_ \"%v\"\n", pkg)
+	var specs []targetSpec
+	if *optAllTargets {
+		specs = configuredTargets(cfg)
+		if len(specs) == 0 {
+			logger.Log(FATAL, "-all-targets: no [[target]] block sets both goos and goarch")
 		}
-		out.WriteString(")\n")
-		if err := os.WriteFile("ryegen_deps.gen.go", out.Bytes(), 0666); err != nil {
-			logger.Log(FATAL, "writing ryegen_deps: %v", err)
+	} else {
+		specs = []targetSpec{{goos: *optGOOS, goarch: *optGOARCH, tags: optTags}}
+	}
+	if *optDedup && !*optAllTargets {
+		logger.Log(FATAL, "-dedup requires -all-targets")
+	}
+	dedupFiles := []string{files.builtins("common"), files.convs("common")}
+	if !*optDedup {
+		// Files shared between targets by a previous -dedup run
+		// would duplicate the declarations of the new files.
+		for _, name := range dedupFiles {
+			generated, err := isFileGeneratedByRyegen(name, files)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				logger.Log(FATAL, "reading %v: %v", name, err)
+			}
+			if !generated {
+				continue
+			}
+			if !*optAllTargets {
+				logger.Log(FATAL, "%v was generated with -dedup; re-run with -all-targets -dedup, or run -clean first", name)
+			}
+			if err := removeFile(name); err != nil {
+				logger.Log(FATAL, "deleting stale %v: %v", name, err)
+			}
 		}
 	}
 
-	pkgs, err := loader.Load(loaderCfg)
+	// Import path of the output package, whose types are
+	// referred to without qualifier
+	basePkg, err := loader.DirPackagePath(&loader.Config{}, files.dir)
 	if err != nil {
-		logger.Log(FATAL, `failed to load packages: %v
-re-running after "go mod tidy" might fix the error`, err)
+		logger.Log(FATAL, "resolving output package: %v", err)
 	}
 
-	basePkg := "main"
-	qualifier := types.Qualifier(func(p *types.Package) string {
-		path := p.Path()
-		if path == basePkg {
-			return ""
+	var explain *regexp.Regexp
+	if *optExplain != "" {
+		explain, err = regexp.Compile(*optExplain)
+		if err != nil {
+			logger.Log(FATAL, "invalid -explain regex: %v", err)
 		}
-		return packagePathToImportName(path)
-	})
-	tset := typeset.New(qualifier)
+	}
 
-	cs := converter.NewConverterSet(tset, basePkg)
+	var rules ruleUsage
 
-	shouldVisitPackage := func(p *packages.Package) bool {
-		for elem := range strings.SplitSeq(p.PkgPath, "/") {
-			if elem == "internal" || elem == "cmd" {
-				return false
-			}
-		}
-		if strings.HasPrefix(p.PkgPath, "vendor/") {
-			// Ignore Go vendored std library modules.
-			// See https://cs.opensource.google/go/go/+/master:src/README.vendor.
-			// TODO: Figure out if this could break
-			// user-vendored modules.
-			return false
-		}
-		return true
-	}
+	// The cache of the previous run, and the package
+	// outputs of this run if they are cached (see [genCache])
+	var prevCache genCache
+	var pkgCache *genCache
+	var inputsKey [sha256.Size]byte
 
-	bset := newBindingSet()
+	// generate generates the bindings for a single target.
+	// The manifest and docs are only written for the
+	// primary target. The builtins and converters are
+	// returned and written once all targets are generated.
+	generate := func(tc targetConfig, primary bool) (res targetOutput) {
+		targetName := tc.spec.name()
+		res.name = targetName
+		res.buildExpr = tc.buildExpr
+		res.deps = tc.deps
 
-	dbgImportGraph := map[string][]string{} // pkg path to imported paths; for debugging
-	{
-		seen := map[string]bool{}
-		var visit func(p *packages.Package) error
-		visit = func(p *packages.Package) error {
-			if seen[p.PkgPath] {
-				return nil
-			}
-			seen[p.PkgPath] = true
-			if !shouldVisitPackage(p) {
-				return nil
+		var cache *genCache
+		if pkgCache != nil {
+			cache = &prevCache
+		}
+		g, err := newTargetGenerator(logger, cfg, tc, basePkg, inputsKey, cache)
+		if err == nil {
+			g.explain = explain
+			err = g.run()
+		}
+		if err != nil {
+			if cfgErr := (&config.Error{}); errors.As(err, &cfgErr) {
+				logger.Log(FATAL, "%v", cfgErr.String())
 			}
+			logger.Log(FATAL, "%v", err)
+		}
+		if pkgCache != nil {
+			logger.Log(INFO, "target %v: generated the bindings of %v of %v packages", targetName, g.generated, len(g.paths))
+			g.store(pkgCache)
+		}
+		defer handleImportGraph(logger, g.importGraph)()
 
-			bfs := makePkgBindings(tset, p.TypesInfo, p.Syntax)
-			bfs, err := bset.addWithRules(cfg, bfs)
+		for _, res := range g.conflictResolutions() {
+			logger.Log(WARN, "resolved naming conflict: %v", res)
+		}
+		rules.add(activeRules(cfg, tc.sources), g.ruleMatches())
+		res.builtins, res.convs = g.code()
+		defer handleEnvConvGraph(logger, g.graph)()
+		if !primary {
+			// The manifest, docs and diff describe
+			// the first target only
+			return res
+		}
+		man := g.manifest(targetName)
+		{
+			data, err := man.Marshal()
 			if err != nil {
-				return fmt.Errorf("failed to apply binding rules: %w", err)
+				logger.Log(FATAL, "encoding manifest: %v", err)
 			}
-
-			// We only want to make bindings for the imports actually
-			// used by at least one of the bindings in this package
-			// (after applying binding rules).
-			usedImports := map[string]bool{}
-			for _, bf := range bfs {
-				// In this case, we're collecting all imports required
-				// to represent the required converter func. I'm pretty
-				// sure - but not 100% - that this should correspond
-				// to collecting all API dependencies.
-				for _, pkg := range collectImports(bf.requiredConverter) {
-					usedImports[pkg.Path()] = true
-				}
+			if err := writeFile(files.manifest(), data); err != nil {
+				logger.Log(FATAL, "writing %v: %v", files.manifest(), err)
 			}
-
-			imports := make([]*packages.Package, 0, len(p.Imports))
-			for _, imp := range p.Imports {
-				if !seen[imp.PkgPath] && usedImports[imp.PkgPath] {
-					imports = append(imports, imp)
-				}
-			}
-			slices.SortFunc(imports, func(a, b *packages.Package) int { return cmp.Compare(a.PkgPath, b.PkgPath) })
-			{
-				paths := make([]string, len(imports))
-				for i, imp := range imports {
-					paths[i] = imp.PkgPath
+		}
+		if *optDocs != "" && !diffMode {
+			if check == nil {
+				if err := os.MkdirAll(*optDocs, 0777); err != nil {
+					logger.Log(FATAL, "creating docs directory: %v", err)
 				}
-				dbgImportGraph[p.PkgPath] = paths
 			}
-			for _, imp := range imports {
-				if err := visit(imp); err != nil {
-					return err
+			pages := makeReferencePages(g.refBindings)
+			for _, name := range slices.Sorted(maps.Keys(pages)) {
+				if err := writeFile(filepath.Join(*optDocs, name), pages[name]); err != nil {
+					logger.Log(FATAL, "writing docs: %v", err)
 				}
 			}
-			return nil
+			logger.Log(INFO, "wrote API reference to %v", *optDocs)
 		}
-		for _, p := range pkgs {
-			if err := visit(p); err != nil {
-				if cfgErr := (&config.Error{}); errors.As(err, &cfgErr) {
-					logger.Log(FATAL, "%v", cfgErr.String())
-				}
-				logger.Log(FATAL, "visit packages: %v", err)
+		if diffMode {
+			parts, err := files.builtinsParts(targetName)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				logger.Log(FATAL, "reading output directory: %v", err)
+			}
+			old, from, err := loadPreviousManifest(flag.Arg(1), files.manifest(), files.builtins(targetName), parts)
+			if err != nil {
+				logger.Log(FATAL, "loading previous bindings: %v", err)
+			}
+			logger.Log(INFO, "comparing against %v", from)
+			diff := diffManifests(old, man)
+			diff.write(os.Stdout)
+			if diff.breaking() {
+				exitCode = 1
 			}
 		}
+		return res
 	}
-	defer handleImportGraph(logger, dbgImportGraph)()
 
-	writeImports := func(w io.Writer, packagePaths []string) {
-		fmt.Fprintf(w, "import (\n")
-		for _, imp := range packagePaths {
-			fmt.Fprintf(w, "\t%v \"%v\"\n", packagePathToImportName(imp), imp)
+	// The release tags must match the Go version the
+	// packages are loaded with, not the one ryegen was
+	// built with
+	goVersion, err := loader.GoVersion()
+	if err != nil {
+		logger.Log(FATAL, "%v", err)
+	}
+	goReleaseTags, err := releaseTags(goVersion)
+	if err != nil {
+		logger.Log(FATAL, "%v", err)
+	}
+	var targets []targetConfig
+	for _, spec := range specs {
+		tc, err := resolveTarget(cfg, spec, goReleaseTags)
+		if err != nil {
+			logger.Log(FATAL, "unable to merge matching targets: %v", err) // this should really not happen, ever.
+		}
+		tc.deps, err = loader.ResolveSources(tc.loaderCfg)
+		if err != nil {
+			logger.Log(FATAL, `failed to resolve sources: %v
+re-running after "go mod tidy" might fix the error`, err)
 		}
-		fmt.Fprintf(w, ")\n\n")
+		targets = append(targets, tc)
 	}
 
-	var goBuildLine string
-	{
-		goBuildLine = "//go:build " + *optGOOS + " && " + *optGOARCH
-		if *target.CGoEnabled {
-			goBuildLine += " && cgo"
+	// The -explain output, the API reference, the diff
+	// and the check require actually generating the
+	// bindings.
+	useCache := !*optNoCache && !dryRun && *optExplain == "" && *optDocs == ""
+	var cachePath string
+	var cacheKey [sha256.Size]byte
+	if useCache {
+		var err error
+		cachePath, err = genCachePath()
+		if err == nil {
+			cacheKey, err = genCacheKey(cfg, targets, basePkg)
 		}
-		if optTags != nil {
-			goBuildLine += " && " + strings.Join(optTags, " && ")
+		if err == nil {
+			inputsKey, err = genInputsKey(cfg, basePkg)
+		}
+		if err != nil {
+			logger.Log(WARN, "not using cache: %v", err)
+			useCache = false
 		}
-		goBuildLine += "\n"
 	}
-
-	bindings := slices.DeleteFunc(bset.bindings, func(bf binding) bool { return bf.props.exclude })
-	bset.invalid = true // bset.bindings may be invalid ... just to be sure
-
-	var code []byte
-	var graph *converter.Graph
-	{
-		packageToBindingFuncs := map[string]map[string]binding{}   // package to func name to bindingFunc
-		packageToBindingConvName := map[string]map[string]string{} // package to func name to conv name
-		for _, fn := range bindings {
-			pkg := fn.props.pkgPath
-			if pkg == "" {
-				// Special pseudo-package for bindings that may not be
-				// package-specific, e.g. struct aliases.
-				pkg = "zz_global"
+	if useCache {
+		cache, ok, err := loadGenCache(cachePath, cacheKey)
+		if err != nil {
+			logger.Log(WARN, "loading cache: %v", err)
+		}
+		if ok {
+			logger.Log(INFO, "inputs unchanged since the last run, using cached files")
+			for _, name := range slices.Sorted(maps.Keys(cache.Files)) {
+				if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+					logger.Log(FATAL, "creating directory: %v", err)
+				}
+				if err := writeFile(name, cache.Files[name]); err != nil {
+					logger.Log(FATAL, "writing %v: %v", name, err)
+				}
 			}
-			convName := cs.Add(fn.requiredConverter, converter.ToRye, pkg+"::"+fn.key())
-			if packageToBindingFuncs[pkg] == nil {
-				packageToBindingFuncs[pkg] = map[string]binding{}
-				packageToBindingConvName[pkg] = map[string]string{}
+			return
+		}
+		cacheFiles = map[string][]byte{}
+		prevCache = cache
+		// Bindings moved between packages and the converter
+		// graph for debugging require generating everything
+		if canCachePackages(cfg) && os.Getenv("RYEGEN_CONV_GRAPH") == "" {
+			pkgCache = &genCache{
+				Packages: map[[sha256.Size]byte]*pkgOutput{},
+				Chunks:   map[[sha256.Size]byte]cachedChunk{},
 			}
-			packageToBindingFuncs[pkg][fn.key()] = fn
-			packageToBindingConvName[pkg][fn.key()] = convName
 		}
+	}
 
-		var convErr *converter.ConverterError
-		code, graph, err = cs.Code()
-		if err != nil {
-			if errors.As(err, &convErr) {
-				logger.Log(WARN, "some converters had errors:\n%v", convErr.String())
-			} else {
-				logger.Log(FATAL, "failed to generate converter code: %v", err)
+	{
+		// The deps files import the packages of the sources, so
+		// "go mod tidy" keeps their modules. Packages that exist on
+		// all targets go into a file without build constraint, the
+		// rest (e.g. of sources with a select constraint) into a
+		// file per target with the target's build constraint, so
+		// they don't break the build of other targets.
+		resolve := func(tc targetConfig, patterns []string) []string {
+			if len(patterns) == 0 {
+				return nil
+			}
+			c := *tc.loaderCfg
+			c.PackagePatterns = patterns
+			pkgs, err := loader.ResolvePatterns(&c)
+			if err != nil {
+				logger.Log(FATAL, "failed to resolve package patterns: %v", err)
 			}
+			return pkgs
 		}
-
-		bindingFuncImports := map[string]struct{}{}
-		for _, fn := range bindings {
-			var pkg string
-			if fn.pkg != nil {
-				pkg = fn.props.pkgPath
+		writeDeps := func(name, buildLine string, deps []*loader.Sources, pkgs []string) {
+			if len(pkgs) == 0 {
+				if generated, err := isFileGeneratedByRyegen(name, files); err == nil && generated {
+					if err := removeFile(name); err != nil {
+						logger.Log(FATAL, "deleting stale %v: %v", name, err)
+					}
+				}
+				return
 			}
-			if !graph.Contains(fn.requiredConverter, converter.ToRye) {
-				delete(packageToBindingFuncs[pkg], fn.key())
-				delete(packageToBindingConvName[pkg], fn.key())
-				continue
+			var out bytes.Buffer
+			out.WriteString(codeGeneratedLine)
+			out.WriteString(generatedFromComment(version, cfg.Hash, deps))
+			out.WriteString(buildLine)
+			out.WriteString(packageLine)
+			out.WriteString("import (\n")
+			for _, pkg := range pkgs {
+				fmt.Fprintf(&out, "\t_ \"%v\"\n", pkg)
 			}
-			for _, imp := range fn.funcCodeImports {
-				bindingFuncImports[imp.Path()] = struct{}{}
+			out.WriteString(")\n")
+			if err := writeFile(name, out.Bytes()); err != nil {
+				logger.Log(FATAL, "writing %v: %v", name, err)
 			}
 		}
 
-		var out bytes.Buffer
-		out.WriteString(codeGeneratedLine(true))
-		out.WriteString(goBuildLine)
-		out.WriteString("package main\n\n")
-		writeImports(&out, slices.Sorted(maps.Keys(bindingFuncImports)))
-		out.WriteString(builtinsCommonCode)
-		for _, pkg := range slices.Sorted(maps.Keys(packageToBindingFuncs)) {
-			bfs := packageToBindingFuncs[pkg]
-			mapName := "builtins_" + packagePathToImportName(pkg)
-			// HACK: Putting the builtins into a map literal directly will cause a compiler error
-			// if there are too many items.
-			// E.g.: "internal compiler error: NewBulk too big: nbit=48093 count=589148 nword=1503 size=885489444"
-			fmt.Fprintf(&out, "var %v = make(map[string]*_env.VarBuiltin, %v)\n", mapName, len(bfs))
-			if len(bfs) > 0 {
-				// Due to the same Go compiler bug, we also have
-				// to break up very large funcs into smaller ones.
-				idxInChunk := 0
-				const chunkSize = 512
-				startChunk := func() {
-					fmt.Fprintf(&out, "func init() {\n")
-					fmt.Fprintf(&out, "\t"+`m := %v`+"\n", mapName)
-				}
-				endChunk := func() {
-					fmt.Fprintf(&out, "}\n\n")
-				}
-
-				startChunk()
-				for _, bf := range slices.Sorted(maps.Keys(bfs)) {
-					if idxInChunk >= chunkSize {
-						idxInChunk = 0
-						endChunk()
-						startChunk()
-					}
-
-					fn := bfs[bf]
-					convName := packageToBindingConvName[pkg][bf]
-					fmt.Fprintf(&out, "\t"+`m["%v"] = %v`+"\n", fn.key(), fn.binding(convName))
-					idxInChunk++
-				}
-				endChunk()
+		// The sources without select constraint are
+		// the same for all targets
+		var patterns []string
+		for _, src := range targets[0].sources {
+			if src.Select == nil {
+				patterns = append(patterns, src.Packages...)
 			}
 		}
-		fmt.Fprintf(&out, "var builtins = make(map[string]map[string]*_env.VarBuiltin, %v)\n", len(packageToBindingFuncs))
-		fmt.Fprintf(&out, "func init() {\n")
-		for _, pkg := range slices.Sorted(maps.Keys(packageToBindingFuncs)) {
-			fmt.Fprintf(&out, "\t"+`builtins["%v"] = builtins_%v`+"\n", pkg, packagePathToImportName(pkg))
+		targetPkgs := make([][]string, len(targets))
+		for i, tc := range targets {
+			targetPkgs[i] = resolve(tc, patterns)
 		}
-		out.WriteString("}\n\n")
-		err := os.WriteFile("ryegen_builtins_"+targetName+".gen.go", out.Bytes(), 0666)
-		if err != nil {
-			logger.Log(FATAL, "writing ryegen_builtins: %v", err)
+		shared, rest := splitCommon(targetPkgs, func(pkg string) string { return pkg })
+		allDeps := make([]*loader.Sources, len(targets))
+		for i, tc := range targets {
+			allDeps[i] = tc.deps
+		}
+		writeDeps(files.deps(""), "", allDeps, shared)
+		for i, tc := range targets {
+			pkgs := append(rest[i], resolve(tc, tc.conditionalPatterns)...)
+			slices.Sort(pkgs)
+			writeDeps(files.deps(tc.spec.name()), "//go:build "+tc.buildExpr+"\n", []*loader.Sources{tc.deps}, slices.Compact(pkgs))
 		}
 	}
-	defer handleEnvConvGraph(logger, graph)()
-	{
+
+	outs := generateTargets(logger, targets, generate)
+	rules.warnUnmatched(logger)
+
+	qualifier := types.Qualifier(func(p *types.Package) string {
+		return packagePathToImportName(p.Path())
+	})
+	write := func(name, buildExpr, generatedFrom string, code []byte) {
 		var out bytes.Buffer
-		out.WriteString(codeGeneratedLine(true))
-		out.WriteString(goBuildLine)
-		out.WriteString("package main\n\n")
+		out.WriteString(codeGeneratedLine)
+		out.WriteString(generatedFrom)
+		out.WriteString("//go:build " + buildExpr + "\n")
+		out.WriteString(packageLine)
 		out.Write(code)
-		if err := os.WriteFile("ryegen_convs_"+targetName+".gen.go", out.Bytes(), 0666); err != nil {
-			logger.Log(FATAL, "writing ryegen_convs: %v", err)
+		if err := writeFile(name, out.Bytes()); err != nil {
+			logger.Log(FATAL, "writing %v: %v", name, err)
+		}
+	}
+	// writeCode writes the builtins and converters of a target
+	// (or the code shared by all targets if target is "common"),
+	// whose packages depend on deps.
+	// Exactly one of the written targets must have helpers.
+	writeCode := func(target, buildExpr string, deps []*loader.Sources, builtins []builtinsEntry, convs []converter.Chunk, helpers bool) {
+		generatedFrom := generatedFromComment(version, cfg.Hash, deps)
+		if !cfg.Output.SplitPackages {
+			write(files.builtins(target), buildExpr, generatedFrom, builtinsCode(builtins, helpers, cfg.Output.Library))
+			write(files.convs(target), buildExpr, generatedFrom, converter.ChunksCode(convs, qualifier))
+			return
+		}
+		write(files.builtins(target), buildExpr, generatedFrom, builtinsCode(nil, helpers, cfg.Output.Library))
+		pkgs, byPkg := groupByPkg(builtins, func(e builtinsEntry) string { return e.pkg })
+		for _, pkg := range pkgs {
+			write(files.builtinsPart(target, pkg), buildExpr, generatedFrom, builtinsCode(byPkg[pkg], false, cfg.Output.Library))
+		}
+		// Converters go into the file of the package of their
+		// type, the prelude and converters of unnamed types into
+		// the main converters file.
+		pkgs, convsByPkg := groupByPkg(convs, func(c converter.Chunk) string { return c.Pkg })
+		write(files.convs(target), buildExpr, generatedFrom, converter.ChunksCode(convsByPkg[""], qualifier))
+		for _, pkg := range pkgs {
+			if pkg != "" {
+				write(files.convsPart(target, pkg), buildExpr, generatedFrom, converter.ChunksCode(convsByPkg[pkg], qualifier))
+			}
+		}
+	}
+
+	if *optDedup {
+		// Code present in all targets goes into files with the
+		// union of the targets' build constraints.
+		exprs := make([]string, len(outs))
+		allBuiltins := make([][]builtinsEntry, len(outs))
+		allConvs := make([][]converter.Chunk, len(outs))
+		allDeps := make([]*loader.Sources, len(outs))
+		for i, out := range outs {
+			exprs[i] = out.buildExpr
+			allDeps[i] = out.deps
+			allBuiltins[i] = out.builtins
+			allConvs[i] = out.convs
+		}
+		commonBuiltins, builtins := splitCommon(allBuiltins, builtinsEntry.id)
+		commonConvs, convs := splitCommon(allConvs, converter.ChunkID)
+		logger.Log(INFO, "%v of %v builtins and %v of %v converter chunks are shared between all targets",
+			len(commonBuiltins), len(allBuiltins[0]), len(commonConvs), len(allConvs[0]))
+
+		writeCode("common", unionBuildExpr(exprs), allDeps, commonBuiltins, commonConvs, true)
+		for i, out := range outs {
+			writeCode(out.name, out.buildExpr, []*loader.Sources{out.deps}, builtins[i], convs[i], false)
+		}
+	} else {
+		for _, out := range outs {
+			writeCode(out.name, out.buildExpr, []*loader.Sources{out.deps}, out.builtins, out.convs, true)
+		}
+	}
+
+	if !diffMode {
+		// Remove the part files of packages that no longer have
+		// any code, or of all packages if the output is no longer
+		// split.
+		targetNames := []string{"common"}
+		for _, out := range outs {
+			targetNames = append(targetNames, out.name)
+		}
+		entries, err := os.ReadDir(files.dir)
+		if err != nil && !(check != nil && errors.Is(err, os.ErrNotExist)) {
+			logger.Log(FATAL, "reading output directory: %v", err)
+		}
+		for _, e := range entries {
+			name := filepath.Join(files.dir, e.Name())
+			if written[name] || !files.isPartName(e.Name(), targetNames) {
+				continue
+			}
+			if generated, err := isFileGeneratedByRyegen(name, files); err != nil || !generated {
+				continue
+			}
+			if err := removeFile(name); err != nil {
+				logger.Log(FATAL, "deleting stale %v: %v", name, err)
+			}
+		}
+	}
+
+	if check != nil {
+		if !check.ok() {
+			fmt.Println("generated files are out of date:")
+			check.write(os.Stdout)
+			exitCode = 1
+		} else {
+			logger.Log(INFO, "generated files are up to date")
+		}
+	}
+
+	if useCache {
+		cache := genCache{Key: cacheKey, Files: cacheFiles}
+		if pkgCache != nil {
+			cache.Packages = pkgCache.Packages
+			cache.Chunks = pkgCache.Chunks
+		}
+		if err := cache.store(cachePath); err != nil {
+			logger.Log(WARN, "storing cache: %v", err)
 		}
 	}
 }
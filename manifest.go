@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/refaktor/ryegen/v2/converter/typeset"
+)
+
+const (
+	manifestFileName = "ryegen_manifest.json"
+	// Incremented on every incompatible change to the manifest format.
+	manifestVersion = 1
+)
+
+// manifest is a machine-readable description of
+// all generated bindings, written to [manifestFileName].
+//
+// The format is stable within a version. Fields may be
+// added without incrementing the version, but never
+// removed or changed in meaning.
+type manifest struct {
+	// Always "ryegen"; identifies the file as generated
+	Generator string `json:"generator"`
+	// See [manifestVersion]
+	Version int `json:"version"`
+	// Target name, e.g. "linux_amd64"
+	Target string `json:"target"`
+	// Sorted by package, then by key
+	Bindings []manifestBinding `json:"bindings"`
+	// Sorted kind names of all natives the converters
+	// can create, e.g. "go(*net_http.Client)"
+	NativeKinds []string `json:"nativeKinds"`
+}
+
+type manifestBinding struct {
+	// Rye package path
+	Package string `json:"package"`
+	// Rye receiver type name (empty if none)
+	Receiver string `json:"receiver,omitempty"`
+	// Rye name (without receiver)
+	Name string `json:"name"`
+	// Full Rye word, e.g. "Client//Do"
+	Key string `json:"key"`
+	// Additional names of the binding
+	Aliases []manifestAlias `json:"aliases,omitempty"`
+	// Fully qualified Go symbol, e.g. "(*net/http.Client).Do"
+	GoSymbol string `json:"goSymbol,omitempty"`
+	// Binding type: "func", "getter", "setter" or "constructor"
+	Type string `json:"type"`
+	// Number of Rye arguments (including the receiver)
+	Arity int `json:"arity"`
+	// Go parameter types (including the receiver)
+	Params []string `json:"params"`
+	// Go result types
+	Results []string `json:"results"`
+	// Whether the last parameter is variadic
+	Variadic bool `json:"variadic,omitempty"`
+
+	// Key of the aliased binding if this is one of its
+	// aliases (only used by [diffManifests])
+	aliasOf string
+}
+
+type manifestAlias struct {
+	// Rye name (without receiver)
+	Name string `json:"name"`
+	// Full Rye word
+	Key string `json:"key"`
+	// Whether using the alias prints a deprecation warning
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// makeManifest creates the manifest of the given bindings
+// (see [manifestBindings]).
+func makeManifest(target string, bindings []manifestBinding, nativeKinds []string) manifest {
+	m := manifest{
+		Generator:   "ryegen",
+		Version:     manifestVersion,
+		Target:      target,
+		Bindings:    []manifestBinding{},
+		NativeKinds: slices.Clone(nativeKinds),
+	}
+	if m.NativeKinds == nil {
+		m.NativeKinds = []string{}
+	}
+	for _, b := range bindings {
+		// Cached bindings have nil slices (see [pkgOutput])
+		if b.Params == nil {
+			b.Params = []string{}
+		}
+		if b.Results == nil {
+			b.Results = []string{}
+		}
+		m.Bindings = append(m.Bindings, b)
+	}
+	slices.SortFunc(m.Bindings, func(a, b manifestBinding) int {
+		return cmp.Or(
+			cmp.Compare(a.Package, b.Package),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+
+	return m
+}
+
+// manifestBindings returns the manifest entries of all
+// generated (not dropped) bindings.
+func manifestBindings(bindings []referenceBinding, tset *typeset.TypeSet) []manifestBinding {
+	var res []manifestBinding
+	for _, b := range bindings {
+		if b.err != nil {
+			continue
+		}
+		sig := b.requiredConverter
+		params := []string{}
+		if sig.Recv() != nil {
+			params = append(params, tset.TypeString(sig.Recv().Type()))
+		}
+		for p := range sig.Params().Variables() {
+			params = append(params, tset.TypeString(p.Type()))
+		}
+		results := []string{}
+		for r := range sig.Results().Variables() {
+			results = append(results, tset.TypeString(r.Type()))
+		}
+		var aliases []manifestAlias
+		for _, a := range b.props.aliases {
+			aliases = append(aliases, manifestAlias{
+				Name:       a.name,
+				Key:        b.props.keyWithName(a.name),
+				Deprecated: a.deprecated,
+			})
+		}
+		res = append(res, manifestBinding{
+			Package:  b.pkg,
+			Receiver: b.props.recv,
+			Name:     b.props.name,
+			Key:      b.key(),
+			Aliases:  aliases,
+			GoSymbol: b.goSymbol,
+			Type:     b.typ.String(),
+			Arity:    len(params),
+			Params:   params,
+			Results:  results,
+			Variadic: sig.Variadic(),
+		})
+	}
+	return res
+}
+
+// Marshal returns the indented JSON encoding of the manifest.
+func (m manifest) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// isManifestGeneratedByRyegen checks whether the file at path is
+// a manifest written by Ryegen.
+func isManifestGeneratedByRyegen(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	var m struct {
+		Generator string `json:"generator"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return false, nil
+	}
+	return m.Generator == "ryegen", nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/converter"
+	"github.com/refaktor/ryegen/v2/loader"
+)
+
+// outputFiles names the generated Go files (see [config.Output]).
+type outputFiles struct {
+	dir      string
+	prefix   string
+	depsFile string
+}
+
+func newOutputFiles(o *config.Output) outputFiles {
+	dir := o.Dir
+	if dir == "" {
+		dir = "."
+	}
+	return outputFiles{
+		dir:      dir,
+		prefix:   o.FilePrefix(),
+		depsFile: o.DepsFileName(),
+	}
+}
+
+// builtins returns the path of the builtins file of a target.
+func (f outputFiles) builtins(target string) string {
+	return filepath.Join(f.dir, f.prefix+"builtins_"+target+".gen.go")
+}
+
+// convs returns the path of the converters file of a target.
+func (f outputFiles) convs(target string) string {
+	return filepath.Join(f.dir, f.prefix+"convs_"+target+".gen.go")
+}
+
+// manifest returns the path of the manifest (see [manifest]).
+func (f outputFiles) manifest() string {
+	return filepath.Join(f.dir, manifestFileName)
+}
+
+// builtinsPart returns the path of the builtins file of a
+// target containing the code of a single Go package
+// (see [config.Output.SplitPackages]).
+func (f outputFiles) builtinsPart(target, pkg string) string {
+	return filepath.Join(f.dir, f.prefix+"builtins_"+target+"."+packagePathToImportName(pkg)+".gen.go")
+}
+
+// convsPart is like [outputFiles.builtinsPart], but for the
+// converters file.
+func (f outputFiles) convsPart(target, pkg string) string {
+	return filepath.Join(f.dir, f.prefix+"convs_"+target+"."+packagePathToImportName(pkg)+".gen.go")
+}
+
+// isPartName returns whether a file name matches the name of a
+// builtins or converters part file of any of targets.
+func (f outputFiles) isPartName(name string, targets []string) bool {
+	for _, target := range targets {
+		for _, kind := range []string{"builtins_", "convs_"} {
+			// e.g. "net_http.gen.go"
+			rest, ok := strings.CutPrefix(name, f.prefix+kind+target+".")
+			if ok && len(rest) > len(".gen.go") && strings.HasSuffix(rest, ".gen.go") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// builtinsParts returns the paths of the existing builtins part
+// files of a target.
+func (f outputFiles) builtinsParts(target string) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, e := range entries {
+		name := e.Name()
+		if f.isPartName(name, []string{target}) && strings.HasPrefix(name, f.prefix+"builtins_") {
+			res = append(res, filepath.Join(f.dir, name))
+		}
+	}
+	return res, nil
+}
+
+// deps returns the path of the file importing the source
+// packages of a target, or of all targets if target is "".
+func (f outputFiles) deps(target string) string {
+	if target == "" {
+		return filepath.Join(f.dir, f.depsFile)
+	}
+	base, ext := f.depsFileBaseExt()
+	return filepath.Join(f.dir, base+"_"+target+ext)
+}
+
+// e.g. "ryegen_deps.gen.go" -> "ryegen_deps", ".gen.go"
+func (f outputFiles) depsFileBaseExt() (base, ext string) {
+	for _, ext := range []string{".gen.go", ".go"} {
+		if base, ok := strings.CutSuffix(f.depsFile, ext); ok {
+			return base, ext
+		}
+	}
+	return f.depsFile, ""
+}
+
+// isGeneratedName returns whether a file name matches the name of
+// any of the generated Go files.
+func (f outputFiles) isGeneratedName(name string) bool {
+	if strings.HasPrefix(name, f.prefix) && strings.HasSuffix(name, ".gen.go") {
+		return true
+	}
+	if name == f.depsFile {
+		return true
+	}
+	base, ext := f.depsFileBaseExt()
+	return strings.HasPrefix(name, base+"_") && strings.HasSuffix(name, ext)
+}
+
+// targetOutput holds the generated code of a single target,
+// split into parts that can be shared between targets (see
+// -dedup) or written to different files.
+type targetOutput struct {
+	name      string
+	buildExpr string
+	deps      *loader.Sources
+	builtins  []builtinsEntry
+	convs     []converter.Chunk
+}
+
+// builtinsEntry is the code registering a single builtin
+// and its aliases in the builtins map m of a Rye package.
+// An entry with an empty key and code only registers the
+// package.
+type builtinsEntry struct {
+	pkg     string // Rye package
+	key     string
+	code    string   // statements
+	imports []string // package paths
+}
+
+func (e builtinsEntry) id() string {
+	return e.pkg + "\x00" + e.key + "\x00" + e.code
+}
+
+// builtinsCode returns the code (without the package clause)
+// of a builtins file. Only one file of the package may contain
+// the helpers (see [builtinsCommonCode] for library).
+func builtinsCode(entries []builtinsEntry, helpers, library bool) []byte {
+	var out bytes.Buffer
+
+	imports := map[string]struct{}{}
+	for _, e := range entries {
+		for _, imp := range e.imports {
+			imports[imp] = struct{}{}
+		}
+	}
+	fmt.Fprintf(&out, "import (\n")
+	for _, imp := range slices.Sorted(maps.Keys(imports)) {
+		fmt.Fprintf(&out, "\t%v \"%v\"\n", packagePathToImportName(imp), imp)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	if helpers {
+		out.WriteString(builtinsCommonCode(library))
+	} else {
+		out.WriteString(builtinsImports)
+		out.WriteString("\n// Force-use the builtins imports.\n")
+		for _, s := range []string{"_fmt.Sprint", "_os.Stderr", "_env.NewVoid", "_evaldo.BuiltinNames"} {
+			fmt.Fprintf(&out, "var _ = %v\n", s)
+		}
+		out.WriteString("var _ _sync.Once\n\n")
+	}
+
+	// Entries are grouped by package and split into init
+	// funcs of at most chunkSize builtins.
+	// HACK: Putting the builtins into a map literal directly will cause a compiler error
+	// if there are too many items.
+	// E.g.: "internal compiler error: NewBulk too big: nbit=48093 count=589148 nword=1503 size=885489444"
+	// Due to the same Go compiler bug, we also have
+	// to break up very large funcs into smaller ones.
+	const chunkSize = 512
+	for i := 0; i < len(entries); {
+		pkg := entries[i].pkg
+		var code strings.Builder
+		for n := 0; n < chunkSize && i < len(entries) && entries[i].pkg == pkg; n++ {
+			for line := range strings.Lines(entries[i].code) {
+				code.WriteString("\t\t" + line)
+			}
+			i++
+		}
+		fmt.Fprintf(&out, "func init() {\n")
+		if code.Len() == 0 {
+			// Only declares the package
+			fmt.Fprintf(&out, "\t"+`addBuiltins(%q, nil)`+"\n", pkg)
+		} else {
+			fmt.Fprintf(&out, "\t"+`addBuiltins(%q, func(m map[string]*_env.VarBuiltin) {`+"\n", pkg)
+			out.WriteString(code.String())
+			fmt.Fprintf(&out, "\t})\n")
+		}
+		fmt.Fprintf(&out, "}\n\n")
+	}
+
+	return out.Bytes()
+}
+
+// groupByPkg groups xs by their package (see pkg), preserving
+// their order. Returns the packages in sorted order.
+func groupByPkg[T any](xs []T, pkg func(T) string) (pkgs []string, groups map[string][]T) {
+	groups = map[string][]T{}
+	for _, x := range xs {
+		groups[pkg(x)] = append(groups[pkg(x)], x)
+	}
+	return slices.Sorted(maps.Keys(groups)), groups
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFiles(t *testing.T) {
+	require := require.New(t)
+
+	files := newOutputFiles(&config.Output{})
+	require.Equal("ryegen_builtins_linux_amd64.gen.go", files.builtins("linux_amd64"))
+	require.Equal("ryegen_convs_common.gen.go", files.convs("common"))
+	require.Equal("ryegen_deps.gen.go", files.deps(""))
+	require.Equal("ryegen_deps_linux_amd64.gen.go", files.deps("linux_amd64"))
+	require.True(files.isGeneratedName("ryegen_convs_linux_amd64.gen.go"))
+	require.False(files.isGeneratedName("main.go"))
+
+	require.Equal("ryegen_builtins_linux_amd64.net_http.gen.go", files.builtinsPart("linux_amd64", "net/http"))
+	require.Equal("ryegen_convs_common.golang_org_x_net.gen.go", files.convsPart("common", "golang.org/x/net"))
+	require.True(files.isGeneratedName(files.convsPart("linux_amd64", "io")))
+	require.True(files.isPartName("ryegen_convs_linux_amd64.io.gen.go", []string{"linux_amd64"}))
+	require.False(files.isPartName("ryegen_convs_linux_amd64.gen.go", []string{"linux_amd64"}))
+	// Target names may be prefixes of each other
+	require.False(files.isPartName("ryegen_convs_linux_amd64_purego.io.gen.go", []string{"linux_amd64"}))
+
+	files = newOutputFiles(&config.Output{Dir: "bind", Prefix: "rg_", DepsFile: "deps.go"})
+	require.Equal(filepath.Join("bind", "rg_builtins_linux_amd64.gen.go"), files.builtins("linux_amd64"))
+	require.Equal(filepath.Join("bind", "deps.go"), files.deps(""))
+	require.Equal(filepath.Join("bind", "deps_windows_amd64.go"), files.deps("windows_amd64"))
+	require.Equal(filepath.Join("bind", "ryegen_manifest.json"), files.manifest())
+	require.True(files.isGeneratedName("rg_convs_linux_amd64.gen.go"))
+	require.True(files.isGeneratedName("deps.go"))
+	require.True(files.isGeneratedName("deps_windows_amd64.go"))
+	require.False(files.isGeneratedName("ryegen_convs_linux_amd64.gen.go"))
+	require.False(files.isGeneratedName("depsx.go"))
+}
+
+func TestGroupByPkg(t *testing.T) {
+	pkgs, groups := groupByPkg([]string{"b.1", "a.1", "b.2"}, func(s string) string {
+		return strings.Split(s, ".")[0]
+	})
+	require.Equal(t, []string{"a", "b"}, pkgs)
+	require.Equal(t, map[string][]string{"a": {"a.1"}, "b": {"b.1", "b.2"}}, groups)
+}
+
+func TestOutputCheck(t *testing.T) {
+	require := require.New(t)
+
+	// The output directory may only be set by -out-dir, so
+	// loading doesn't check the output settings
+	path := filepath.Join(t.TempDir(), "ryegen.toml")
+	require.NoError(os.WriteFile(path, []byte("[output]\nlibrary = true\n"), 0666))
+	cfg, err := config.Load(path)
+	require.NoError(err)
+	_, err = cfg.Output.PackageName()
+	require.ErrorContains(err, "library mode needs a package name")
+	require.ErrorContains(cfg.Output.Check(), "library mode needs a package name")
+
+	cfg.Output.Dir = "bind/ryebind"
+	name, err := cfg.Output.PackageName()
+	require.NoError(err)
+	require.Equal("ryebind", name)
+	require.NoError(cfg.Output.Check())
+
+	name, err = (&config.Output{}).PackageName()
+	require.NoError(err)
+	require.Equal("main", name)
+	require.ErrorContains((&config.Output{Package: "lib"}).Check(), "package must be main")
+	require.ErrorContains((&config.Output{Library: true, Package: "main"}).Check(), "can't be main")
+}
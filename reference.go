@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// referenceBinding is a binding listed in the API reference.
+type referenceBinding struct {
+	binding
+	// Rye package the binding is registered in
+	pkg string
+	// Reason the binding was dropped (nil if it was generated)
+	err error
+}
+
+// makeReferencePages creates a Markdown API reference with one
+// page per Rye package and an index page linking to all of them.
+// Returns a map of file names to file contents.
+func makeReferencePages(bindings []referenceBinding) map[string][]byte {
+	byPkg := map[string][]referenceBinding{}
+	for _, b := range bindings {
+		byPkg[b.pkg] = append(byPkg[b.pkg], b)
+	}
+
+	// Page names are unique and never "index", even if
+	// package paths map to the same name
+	pageNames := map[string]string{}
+	used := map[string]bool{"index": true}
+	for _, pkg := range slices.Sorted(maps.Keys(byPkg)) {
+		base := packagePathToImportName(pkg)
+		name := base
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%v_%v", base, i)
+		}
+		used[name] = true
+		pageNames[pkg] = name + ".md"
+	}
+	pageName := func(pkg string) string { return pageNames[pkg] }
+
+	pages := map[string][]byte{}
+
+	var index bytes.Buffer
+	index.WriteString("# Rye packages\n\n")
+	index.WriteString("| Package | Bindings | Dropped |\n")
+	index.WriteString("|---|---|---|\n")
+	for _, pkg := range slices.Sorted(maps.Keys(byPkg)) {
+		bs := byPkg[pkg]
+		slices.SortFunc(bs, func(a, b referenceBinding) int {
+			return cmp.Compare(a.key(), b.key())
+		})
+		var emitted, dropped []referenceBinding
+		for _, b := range bs {
+			if b.err == nil {
+				emitted = append(emitted, b)
+			} else {
+				dropped = append(dropped, b)
+			}
+		}
+		fmt.Fprintf(&index, "| [%v](%v) | %v | %v |\n",
+			markdownCode(pkg), pageName(pkg), len(emitted), len(dropped))
+
+		var page bytes.Buffer
+		fmt.Fprintf(&page, "# %v\n\n", markdownCode(pkg))
+		fmt.Fprintf(&page, "Import with `import\\go %q`.\n\n", pkg)
+		writeSection := func(title string, bs []referenceBinding) {
+			if len(bs) == 0 {
+				return
+			}
+			fmt.Fprintf(&page, "## %v\n\n", title)
+			for _, b := range bs {
+				writeReferenceBinding(&page, b)
+			}
+		}
+		writeSection("Bindings", emitted)
+		writeSection("Dropped bindings", dropped)
+		pages[pageName(pkg)] = page.Bytes()
+	}
+	pages["index.md"] = index.Bytes()
+
+	return pages
+}
+
+func writeReferenceBinding(w *bytes.Buffer, b referenceBinding) {
+	fmt.Fprintf(w, "### %v\n\n", markdownCode(b.key()))
+	fmt.Fprintf(w, "- Kind: %v\n", b.typ)
+	for _, a := range b.props.aliases {
+		var deprecated string
+		if a.deprecated {
+			deprecated = " (deprecated)"
+		}
+		fmt.Fprintf(w, "- Alias: %v%v\n", markdownCode(b.props.keyWithName(a.name)), deprecated)
+	}
+	if b.goSymbol != "" {
+		fmt.Fprintf(w, "- Go symbol: %v\n", markdownCode(b.goSymbol))
+	}
+	if b.goDecl != "" {
+		fmt.Fprintf(w, "- Go declaration: %v\n", markdownCode(b.goDecl))
+	}
+	if b.err != nil {
+		fmt.Fprintf(w, "- Dropped: %v\n", markdownCode(b.err.Error()))
+	}
+	w.WriteString("\n")
+	if doc := strings.TrimSpace(b.doc); doc != "" {
+		w.WriteString(doc)
+		w.WriteString("\n\n")
+	}
+}
+
+// markdownCode returns s as inline Markdown code.
+func markdownCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
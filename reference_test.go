@@ -0,0 +1,78 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/converter"
+	"github.com/refaktor/ryegen/v2/converter/typeset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeReferencePages(t *testing.T) {
+	require := require.New(t)
+
+	const src = `package main
+
+// Add adds a and b.
+func Add(a, b int) int { return a + b }
+
+// Greet greets g.
+func Greet(g interface{ SayHello() }) {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	require.NoError(err)
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Uses:  map[*ast.Ident]types.Object{},
+		Defs:  map[*ast.Ident]types.Object{},
+	}
+	_, err = (&types.Config{}).Check("main", fset, []*ast.File{f}, info)
+	require.NoError(err)
+	docs := docIndex{}
+	docs.add(info, []*ast.File{f})
+
+	tset := typeset.New(func(p *types.Package) string {
+		if p.Path() == "main" {
+			return ""
+		}
+		return packagePathToImportName(p.Path())
+	})
+	cs := converter.NewConverterSet(tset, "main")
+	bindings := makePkgBindings(tset, info, []*ast.File{f}, docs)
+	for _, bf := range bindings {
+		cs.Add(bf.requiredConverter, converter.ToRye, bf.key())
+	}
+	_, graph, _ := cs.Code()
+
+	var refBindings []referenceBinding
+	for _, bf := range bindings {
+		refBindings = append(refBindings, referenceBinding{
+			binding: bf,
+			pkg:     "example.com",
+			err:     graph.Error(bf.requiredConverter, converter.ToRye),
+		})
+	}
+	// Must not overwrite the index page
+	refBindings = append(refBindings, referenceBinding{binding: bindings[0], pkg: "index"})
+
+	pages := makeReferencePages(refBindings)
+	require.ElementsMatch([]string{"index.md", "example_com.md", "index_2.md"}, slices.Collect(maps.Keys(pages)))
+
+	index := string(pages["index.md"])
+	require.Contains(index, "| [`example.com`](example_com.md) | 1 | 1 |\n")
+	require.Contains(index, "| [`index`](index_2.md) | 1 | 0 |\n")
+
+	page := string(pages["example_com.md"])
+	require.Contains(page, "## Bindings\n\n### `Add`\n")
+	require.Contains(page, "Add adds a and b.")
+	require.Contains(page, "## Dropped bindings\n\n### `Greet`\n")
+	require.Regexp("- Dropped: `.*no known converter template for type interface\\{SayHello\\(\\)\\}`\n", page)
+	require.Contains(page, "Greet greets g.")
+}
@@ -11,6 +11,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -78,7 +80,9 @@ func checkFile(t *testing.T, dir, name string) {
 	tset := typeset.New(qualifier)
 	cs := converter.NewConverterSet(tset, basePkg)
 
-	bindings := makePkgBindings(tset, info, []*ast.File{f})
+	docs := docIndex{}
+	docs.add(info, []*ast.File{f})
+	bindings := makePkgBindings(tset, info, []*ast.File{f}, docs)
 
 	var cfg *config.Config
 	if _, err := os.Stat(configPath); err == nil {
@@ -94,11 +98,15 @@ func checkFile(t *testing.T, dir, name string) {
 
 	if cfg != nil {
 		bset := newBindingSet()
-		newBindings, err := bset.addWithRules(cfg, bindings)
+		newBindings, err := bset.addWithRules(cfg, cfg.AllRules(), bindings)
 		require.NoError(err)
-		bindings = newBindings
+		bindings = slices.DeleteFunc(newBindings, func(bf binding) bool { return bf.props.exclude })
+		cs.SetNativeKindNames(bset.recvNames)
+		cs.SetReflectTypes(cfg.ReflectTypes())
 	}
 
+	withDocs := cfg == nil || cfg.Docs == nil || *cfg.Docs
+
 	var expectedErrors string
 	if _, err := os.Stat(expectedErrorsPath); err == nil {
 		b, err := os.ReadFile(expectedErrorsPath)
@@ -147,22 +155,58 @@ func checkFile(t *testing.T, dir, name string) {
 		require.Equal(expect, got, "expected errors must match actual errors (specify errors in <name>.expected_errors)")
 	}
 
+	expectedManifestPath := filepath.Join(dir, name+".expected_manifest.json")
+	if _, err := os.Stat(expectedManifestPath); err == nil {
+		var refBindings []referenceBinding
+		for _, fn := range bindings {
+			refBindings = append(refBindings, referenceBinding{
+				binding: fn,
+				pkg:     "example.com",
+				err:     graph.Error(fn.requiredConverter, converter.ToRye),
+			})
+		}
+		got, err := makeManifest("test", manifestBindings(refBindings, tset), graph.NativeKinds()).Marshal()
+		require.NoError(err)
+		if os.Getenv("RYEGEN_UPDATE_MANIFEST") != "" {
+			require.NoError(os.WriteFile(expectedManifestPath, got, 0666))
+		}
+		expect, err := os.ReadFile(expectedManifestPath)
+		require.NoError(err)
+		require.Equal(string(expect), string(got), "manifest doesn't match")
+	} else {
+		require.ErrorIs(err, os.ErrNotExist)
+	}
+
 	builtinsFileName := name + ".out_builtins.go"
 	{
 		var out bytes.Buffer
 		out.WriteString("package main\n\n")
-		out.WriteString(builtinsCommonCode)
+		out.WriteString(builtinsCommonCode(false))
 		out.WriteString("var builtins0 = map[string]*_env.VarBuiltin{\n")
 		for i, fn := range bindings {
 			if !graph.Contains(fn.requiredConverter, converter.ToRye) {
-				//fmt.Println("skipped builtin", fmt.Sprintf("\t"+`"%v": %v,`, fn.key(), fn.binding(bindingConvNames[i])))
+				//fmt.Println("skipped builtin", fmt.Sprintf("\t"+`"%v": %v,`, fn.key(), fn.binding(bindingConvNames[i], true)))
 				continue
 			}
 			require.NoError(err)
-			fmt.Fprintf(&out, "\t"+`"%v": %v,`+"\n", fn.key(), fn.binding(bindingConvNames[i]))
+			fmt.Fprintf(&out, "\t"+`%q: %v,`+"\n", fn.key(), fn.binding(bindingConvNames[i], withDocs))
 		}
 		out.WriteString("}\n\n")
-		out.WriteString(`var builtins = map[string]map[string]*_env.VarBuiltin{"example.com": builtins0}` + "\n\n")
+		out.WriteString("func init() {\n")
+		out.WriteString("\t" + `addBuiltins("example.com", func(m map[string]*_env.VarBuiltin) {` + "\n")
+		out.WriteString("\t\tfor k, v := range builtins0 {\n")
+		out.WriteString("\t\t\tm[k] = v\n")
+		out.WriteString("\t\t}\n")
+		for _, fn := range bindings {
+			if !graph.Contains(fn.requiredConverter, converter.ToRye) {
+				continue
+			}
+			for _, alias := range fn.props.aliases {
+				fmt.Fprintf(&out, "\t\t%v\n", fn.aliasCode(alias))
+			}
+		}
+		out.WriteString("\t})\n")
+		out.WriteString("}\n\n")
 		err := os.WriteFile(filepath.Join(dir, builtinsFileName), out.Bytes(), 0666)
 		require.NoError(err)
 	}
@@ -202,3 +246,261 @@ func TestRyegen(t *testing.T) {
 		}
 	}
 }
+
+// TestRegisterMultiple registers two binding sets generated
+// into different packages in one program state and imports a
+// package of each.
+func TestRegisterMultiple(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	goMod, err := os.ReadFile(filepath.Join("testdata", "go.mod"))
+	require.NoError(err)
+	goMod = bytes.Replace(goMod, []byte("module main\n"), []byte("module example.com/register\n"), 1)
+	require.NoError(os.WriteFile(filepath.Join(dir, "go.mod"), goMod, 0666))
+	goSum, err := os.ReadFile(filepath.Join("testdata", "go.sum"))
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0666))
+
+	for _, name := range []string{"one", "two"} {
+		pkgDir := filepath.Join(dir, name)
+		require.NoError(os.Mkdir(pkgDir, 0777))
+
+		entry := func(pkg, key string) builtinsEntry {
+			return builtinsEntry{
+				pkg:  pkg,
+				key:  key,
+				code: fmt.Sprintf(`m[%q] = &_env.VarBuiltin{Argsn: 0, Fn: func(ps *_env.ProgramState, _ ..._env.Object) _env.Object { return *_env.NewString(%q) }}`+"\n", key, name),
+			}
+		}
+		// Both have example.com/shared, with one common word
+		entries := []builtinsEntry{
+			entry("example.com/"+name, "Name"),
+			entry("example.com/shared", "Name"),
+			entry("example.com/shared", "Only"+strings.ToUpper(name[:1])+name[1:]),
+		}
+		code := append([]byte("package "+name+"\n\n"), builtinsCode(entries, true, true)...)
+		require.NoError(os.WriteFile(filepath.Join(pkgDir, "builtins.go"), code, 0666))
+
+		cs := converter.NewConverterSet(typeset.New(nil), "example.com/register/"+name)
+		convs, _, err := cs.Code()
+		require.NoError(err)
+		code = append([]byte("package "+name+"\n\n"), convs...)
+		require.NoError(os.WriteFile(filepath.Join(pkgDir, "converters.go"), code, 0666))
+	}
+
+	// The bindings registered last look up the
+	// packages they don't have in the ones before
+	mainCode := `package main
+
+import (
+	"example.com/register/one"
+	"example.com/register/two"
+
+	"github.com/refaktor/rye/env"
+	"github.com/refaktor/rye/runner"
+)
+
+func main() {
+	runner.DoMain(func(ps *env.ProgramState) error {
+		one.Register(ps)
+		two.Register(ps)
+		return nil
+	})
+}
+`
+	require.NoError(os.WriteFile(filepath.Join(dir, "main.go"), []byte(mainCode), 0666))
+	ryeCode := `
+one: import\go "example.com/one"
+two: import\go "example.com/two"
+print do\in one { Name }
+print do\in two { Name }
+print try { import\go "example.com/three" }
+shared: import\go "example.com/shared"
+print do\in shared { OnlyOne }
+print do\in shared { OnlyTwo }
+print do\in shared { Name }
+`
+	require.NoError(os.WriteFile(filepath.Join(dir, "main.rye"), []byte(ryeCode), 0666))
+
+	cmd := exec.Command("go", "run", ".", "main.rye")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err, ok := err.(*exec.ExitError); ok {
+		t.Fatalf("non-zero exit code; stderr: %s", err.Stderr)
+	}
+	require.NoError(err)
+	require.Equal("one\ntwo\nError: unknown Go package \"example.com/three\" \none\ntwo\none\n", string(output))
+}
+
+// TestAllTargets runs ryegen -all-targets on a module with
+// three targets, two of which select the same files.
+// buildRyegen builds the ryegen binary and returns its path.
+func buildRyegen(t *testing.T) string {
+	ryegen := filepath.Join(t.TempDir(), "ryegen")
+	output, err := exec.Command("go", "build", "-o", ryegen, ".").CombinedOutput()
+	require.NoError(t, err, "%s", output)
+	return ryegen
+}
+
+// writeFiles writes files (path to contents) into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0777))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0666))
+	}
+}
+
+func TestAllTargets(t *testing.T) {
+	require := require.New(t)
+
+	ryegen := buildRyegen(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/targets\n\ngo 1.23\n",
+		"lib/lib.go": `package lib
+
+import "fmt"
+
+func Hello() string { return fmt.Sprint("hello") }
+`,
+		"lib/extra.go": `//go:build extra
+
+package lib
+
+func Extra() {}
+`,
+		"ryegen.toml": `[[source]]
+packages = ['example.com/targets/lib']
+
+[[rule]]
+select.name = 'Extra'
+action.rename = 'extra'
+
+[[rule]]
+select.name = 'Missing'
+action.include = false
+
+[[target]]
+goos = 'linux'
+goarch = 'amd64'
+
+[[target]]
+goos = 'linux'
+goarch = 'amd64'
+tags = ['unused']
+
+[[target]]
+goos = 'linux'
+goarch = 'amd64'
+tags = ['extra']
+`,
+	}
+	writeFiles(t, dir, files)
+
+	cmd := exec.Command(ryegen, "-v", "-all-targets", "-no-cache")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(err, "%s", output)
+	log := string(output)
+
+	// The target with the unused tag loads the same packages
+	require.Contains(log, "INFO: generating target linux_amd64\n")
+	require.Contains(log, "INFO: target linux_amd64_unused loads the same packages as linux_amd64, reusing its bindings\n")
+	require.Contains(log, "INFO: generating target linux_amd64_extra\n")
+	require.Equal(2, strings.Count(log, "generating target"))
+
+	// Only matched on one target
+	require.NotContains(log, "ryegen.toml:4:1")
+	require.Contains(log, "WARNING: ryegen.toml:8:1: rule never matched any binding\n")
+
+	// All targets have the same deps
+	generated, err := filepath.Glob(filepath.Join(dir, "ryegen_deps*"))
+	require.NoError(err)
+	require.Equal([]string{filepath.Join(dir, "ryegen_deps.gen.go")}, generated)
+
+	builtins := func(target string) string {
+		b, err := os.ReadFile(filepath.Join(dir, "ryegen_builtins_"+target+".gen.go"))
+		require.NoError(err)
+		return string(b)
+	}
+	require.Contains(builtins("linux_amd64_unused"), "//go:build linux && amd64 && unused\n")
+	require.Contains(builtins("linux_amd64_unused"), `"Hello"`)
+	require.NotContains(builtins("linux_amd64_unused"), `"extra"`)
+	require.Contains(builtins("linux_amd64_extra"), `"extra"`)
+}
+
+func TestPackageCache(t *testing.T) {
+	require := require.New(t)
+
+	ryegen := buildRyegen(t)
+	cacheDir := t.TempDir()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/cache\n\ngo 1.23\n",
+		"lib/lib.go": `package lib
+
+import "net/url"
+
+func Parse(s string) (*url.URL, error) { return url.Parse(s) }
+`,
+		"util/util.go": `package util
+
+func Upper(s string) string { return s }
+`,
+		"ryegen.toml": `[[source]]
+packages = ['example.com/cache/lib', 'example.com/cache/util']
+
+[[rule]]
+select.recv = '.*'
+action.to-casing.mode = 'kebab'
+action.to-casing.target = 'recv'
+`,
+	})
+	run := func(args ...string) string {
+		cmd := exec.Command(ryegen, append([]string{"-v", "-goos", "linux", "-goarch", "amd64"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheDir)
+		output, err := cmd.CombinedOutput()
+		require.NoError(err, "%s", output)
+		return string(output)
+	}
+	readGenerated := func() map[string]string {
+		names, err := filepath.Glob(filepath.Join(dir, "ryegen_*"))
+		require.NoError(err)
+		res := map[string]string{}
+		for _, name := range names {
+			b, err := os.ReadFile(name)
+			require.NoError(err)
+			res[filepath.Base(name)] = string(b)
+		}
+		return res
+	}
+	generatedRe := regexp.MustCompile(`generated the bindings of (\d+) of (\d+) packages`)
+
+	m := generatedRe.FindStringSubmatch(run())
+	require.NotNil(m)
+	require.Equal(m[2], m[1])
+	require.Contains(run(), "inputs unchanged since the last run")
+
+	// Only the changed package is generated again
+	writeFiles(t, dir, map[string]string{
+		"util/util.go": `package util
+
+func Upper(s string) string { return s }
+
+func Lower(s string) string { return s }
+`,
+	})
+	m = generatedRe.FindStringSubmatch(run())
+	require.NotNil(m)
+	require.Equal("1", m[1])
+	cached := readGenerated()
+	require.Contains(cached["ryegen_builtins_linux_amd64.gen.go"], "util.Lower")
+	require.Contains(cached["ryegen_builtins_linux_amd64.gen.go"], "url.Parse")
+
+	// The merged output is the same as without cache
+	log := run("-no-cache")
+	require.NotRegexp(generatedRe, log)
+	require.Equal(cached, readGenerated())
+}
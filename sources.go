@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"golang.org/x/tools/go/packages"
+)
+
+// sourceScope holds the packages the rules of a
+// [config.Source] apply to.
+type sourceScope struct {
+	rules []*config.Rule
+	pkgs  map[string]bool // package paths
+}
+
+// makeSourceScopes determines the packages the rules of each
+// of the sources (of cfg, selected for the target) apply to.
+// Sources without rules are skipped. resolve resolves package
+// patterns to package paths (see [loader.ResolvePatterns]), and
+// pkgs are the loaded packages, which are used to look up
+// dependencies.
+func makeSourceScopes(sources []config.Source, resolve func(patterns []string) ([]string, error), pkgs []*packages.Package) ([]sourceScope, error) {
+	var byPath map[string]*packages.Package // only initialized if needed
+
+	var res []sourceScope
+	for i := range sources {
+		src := &sources[i]
+		if len(src.Rules) == 0 {
+			continue
+		}
+		roots, err := resolve(src.Packages)
+		if err != nil {
+			return nil, err
+		}
+		scope := sourceScope{pkgs: map[string]bool{}}
+		for j := range src.Rules {
+			scope.rules = append(scope.rules, &src.Rules[j])
+		}
+		for _, path := range roots {
+			scope.pkgs[path] = true
+		}
+		if src.RulesIncludeDeps {
+			if byPath == nil {
+				byPath = map[string]*packages.Package{}
+				packages.Visit(pkgs, nil, func(p *packages.Package) {
+					byPath[p.PkgPath] = p
+				})
+			}
+			var visit func(p *packages.Package)
+			visit = func(p *packages.Package) {
+				for _, imp := range p.Imports {
+					if !scope.pkgs[imp.PkgPath] {
+						scope.pkgs[imp.PkgPath] = true
+						visit(imp)
+					}
+				}
+			}
+			for _, path := range roots {
+				if p, ok := byPath[path]; ok {
+					visit(p)
+				}
+			}
+		}
+		res = append(res, scope)
+	}
+	return res, nil
+}
+
+// rulesForPackage returns the rules that apply to the package
+// at pkgPath: the global rules, followed by the rules of all
+// sources whose scope contains the package.
+func rulesForPackage(cfg *config.Config, scopes []sourceScope, pkgPath string) []*config.Rule {
+	res := cfg.GlobalRules()
+	for _, scope := range scopes {
+		if scope.pkgs[pkgPath] {
+			res = append(res, scope.rules...)
+		}
+	}
+	return slices.Clip(res)
+}
+
+// activeRules returns the global rules, followed by the
+// rules of the given sources (of cfg, selected for the target).
+func activeRules(cfg *config.Config, sources []config.Source) []*config.Rule {
+	res := cfg.GlobalRules()
+	for i := range sources {
+		for j := range sources[i].Rules {
+			res = append(res, &sources[i].Rules[j])
+		}
+	}
+	return res
+}
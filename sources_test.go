@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSourceScopes(t *testing.T) {
+	require := require.New(t)
+
+	// lib -> lib/util -> bytes
+	bytesPkg := &packages.Package{PkgPath: "bytes"}
+	util := &packages.Package{PkgPath: "example.com/lib/util", Imports: map[string]*packages.Package{"bytes": bytesPkg}}
+	lib := &packages.Package{PkgPath: "example.com/lib", Imports: map[string]*packages.Package{"example.com/lib/util": util}}
+	io := &packages.Package{PkgPath: "io"}
+
+	cfg := &config.Config{
+		Rules: []config.Rule{{}},
+		Sources: []config.Source{
+			{Packages: []string{"example.com/lib"}, Rules: []config.Rule{{}, {}}},
+			{Packages: []string{"example.com/lib"}, Rules: []config.Rule{{}}, RulesIncludeDeps: true},
+			{Packages: []string{"io"}},
+		},
+	}
+	resolve := func(patterns []string) ([]string, error) {
+		return patterns, nil
+	}
+	scopes, err := makeSourceScopes(cfg.Sources, resolve, []*packages.Package{lib, io})
+	require.NoError(err)
+	require.Len(scopes, 2)
+
+	global := &cfg.Rules[0]
+	own := []*config.Rule{&cfg.Sources[0].Rules[0], &cfg.Sources[0].Rules[1]}
+	withDeps := &cfg.Sources[1].Rules[0]
+
+	require.Equal(append([]*config.Rule{global}, append(own, withDeps)...), rulesForPackage(cfg, scopes, "example.com/lib"))
+	require.Equal([]*config.Rule{global, withDeps}, rulesForPackage(cfg, scopes, "example.com/lib/util"))
+	require.Equal([]*config.Rule{global, withDeps}, rulesForPackage(cfg, scopes, "bytes"))
+	require.Equal([]*config.Rule{global}, rulesForPackage(cfg, scopes, "io"))
+}
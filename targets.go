@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/mergo"
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/refaktor/ryegen/v2/loader"
+)
+
+// unixOS lists the GOOS values matched by the "unix"
+// build tag (see go/build).
+var unixOS = []string{
+	"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos",
+	"ios", "linux", "netbsd", "openbsd", "solaris",
+}
+
+// buildTags returns the build tags satisfied when building
+// for the given OS, architecture and additional tags, the
+// same way as "go build": GOOS and GOARCH, "unix" for Unix
+// systems, "linux" on android, "solaris" on illumos and
+// "darwin" on ios, the compiler, "cgo" if cgo is enabled and
+// the Go release tags (see [releaseTags]).
+func buildTags(goos, goarch string, cgo bool, extraTags, releaseTags []string) []string {
+	var tags []string
+	tags = append(tags, goos, goarch)
+	if slices.Contains(unixOS, goos) {
+		tags = append(tags, "unix")
+	}
+	switch goos {
+	case "android":
+		tags = append(tags, "linux")
+	case "illumos":
+		tags = append(tags, "solaris")
+	case "ios":
+		tags = append(tags, "darwin")
+	}
+	tags = append(tags, extraTags...)
+	if !slices.Contains(tags, "gc") && !slices.Contains(tags, "gccgo") {
+		tags = append(tags, runtime.Compiler)
+	}
+	if cgo {
+		tags = append(tags, "cgo")
+	}
+	tags = append(tags, releaseTags...)
+	return tags
+}
+
+var reGoVersion = regexp.MustCompile(`\bgo1\.([0-9]+)`)
+
+// releaseTags returns the release tags satisfied by the Go
+// version goVersion (as reported by "go env GOVERSION"), e.g.
+// "go1.1" to "go1.21" for "go1.21.5".
+func releaseTags(goVersion string) ([]string, error) {
+	m := reGoVersion.FindStringSubmatch(goVersion)
+	if m == nil {
+		return nil, fmt.Errorf("unknown Go version %q", goVersion)
+	}
+	minor, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("unknown Go version %q", goVersion)
+	}
+	var res []string
+	for i := 1; i <= minor; i++ {
+		res = append(res, "go1."+strconv.Itoa(i))
+	}
+	return res, nil
+}
+
+// constraintSatisfied returns whether c is nil or
+// satisfied by tags.
+func constraintSatisfied(c *config.Constraint, tags []string) bool {
+	return c == nil || c.Eval(func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+}
+
+// targetSpec is a concrete platform to generate bindings for.
+type targetSpec struct {
+	goos, goarch string
+	tags         []string // additional build tags
+}
+
+// name returns the name used in generated file names,
+// e.g. "linux_amd64".
+func (t targetSpec) name() string {
+	return strings.Join(append([]string{t.goos, t.goarch}, t.tags...), "_")
+}
+
+// configuredTargets returns the concrete targets declared by
+// [[target]] blocks (i.e. ones that set goos and goarch),
+// without duplicates.
+func configuredTargets(cfg *config.Config) []targetSpec {
+	var res []targetSpec
+	seen := map[string]bool{}
+	for _, tgt := range cfg.Targets {
+		if tgt.GOOS == "" || tgt.GOARCH == "" {
+			continue
+		}
+		spec := targetSpec{goos: tgt.GOOS, goarch: tgt.GOARCH, tags: tgt.Tags}
+		if !seen[spec.name()] {
+			seen[spec.name()] = true
+			res = append(res, spec)
+		}
+	}
+	return res
+}
+
+// targetBlockApplies returns whether the options of a
+// [[target]] block apply to the target spec, whose
+// satisfied build tags are tags.
+func targetBlockApplies(tgt config.Target, spec targetSpec, tags []string) bool {
+	if tgt.GOOS != "" && tgt.GOOS != spec.goos ||
+		tgt.GOARCH != "" && tgt.GOARCH != spec.goarch {
+		return false
+	}
+	for _, tag := range tgt.Tags {
+		if !slices.Contains(spec.tags, tag) {
+			return false
+		}
+	}
+	return constraintSatisfied(tgt.Select, tags)
+}
+
+// targetConfig is the configuration of a target spec,
+// resolved from the [[target]] and [[source]] blocks.
+type targetConfig struct {
+	spec      targetSpec
+	cgo       bool
+	tags      []string // satisfied build tags
+	buildExpr string   // build constraint of the target's files
+	// Sources selected for the target, and the package
+	// patterns of those that are only selected for some
+	// targets
+	sources             []config.Source
+	sourceIdxs          []int // indices of sources in the config
+	conditionalPatterns []string
+	loaderCfg           *loader.Config
+	// What the loaded packages depend on; resolved
+	// separately, since it has to run the go command
+	deps *loader.Sources
+}
+
+// resolveTarget resolves the configuration of spec. The
+// release tags are those of the Go version used for loading.
+func resolveTarget(cfg *config.Config, spec targetSpec, releaseTags []string) (targetConfig, error) {
+	target := config.Target{
+		CGoEnabled: new(bool),
+	}
+	// Whether cgo is enabled depends on the [[target]] blocks,
+	// so their select constraints can't use the cgo tag.
+	tags := buildTags(spec.goos, spec.goarch, false, spec.tags, releaseTags)
+	for _, tgt := range cfg.Targets {
+		if targetBlockApplies(tgt, spec, tags) {
+			if err := mergo.Merge(&target, &tgt); err != nil {
+				return targetConfig{}, err
+			}
+		}
+	}
+	res := targetConfig{
+		spec: spec,
+		cgo:  *target.CGoEnabled,
+		tags: buildTags(spec.goos, spec.goarch, *target.CGoEnabled, spec.tags, releaseTags),
+	}
+
+	res.buildExpr = spec.goos + " && " + spec.goarch
+	if res.cgo {
+		res.buildExpr += " && cgo"
+	}
+	if spec.tags != nil {
+		res.buildExpr += " && " + strings.Join(spec.tags, " && ")
+	}
+
+	res.loaderCfg = &loader.Config{}
+	for i, src := range cfg.Sources {
+		if !constraintSatisfied(src.Select, res.tags) {
+			continue
+		}
+		res.sources = append(res.sources, src)
+		res.sourceIdxs = append(res.sourceIdxs, i)
+		res.loaderCfg.PackagePatterns = append(res.loaderCfg.PackagePatterns,
+			src.Packages...)
+		if src.Select != nil {
+			res.conditionalPatterns = append(res.conditionalPatterns, src.Packages...)
+		}
+	}
+	res.loaderCfg.Env = append(res.loaderCfg.Env,
+		"GOOS="+spec.goos,
+		"GOARCH="+spec.goarch,
+		"CGO_ENABLED="+boolToBinStr(res.cgo),
+	)
+	res.loaderCfg.BuildFlags = append(res.loaderCfg.BuildFlags,
+		"-tags="+strings.Join(spec.tags, ","),
+	)
+	return res, nil
+}
+
+// sharesPackages returns whether tc selects the same sources
+// and loads the same packages as other (see
+// [loader.Sources.Packages]), so their bindings are the same.
+func (tc targetConfig) sharesPackages(other targetConfig) bool {
+	return slices.Equal(tc.sourceIdxs, other.sourceIdxs) &&
+		tc.deps.Packages == other.deps.Packages
+}
+
+// generateTargets generates the output of each target using
+// generate, whose primary target is the first one. A target
+// that shares its packages with an earlier one reuses the
+// earlier target's output, so each distinct set of packages
+// is only loaded and generated once.
+func generateTargets(logger *Logger, targets []targetConfig, generate func(tc targetConfig, primary bool) targetOutput) []targetOutput {
+	var outs []targetOutput
+	for i, tc := range targets {
+		if j := slices.IndexFunc(targets[:i], tc.sharesPackages); j >= 0 {
+			logger.Log(INFO, "target %v loads the same packages as %v, reusing its bindings", tc.spec.name(), outs[j].name)
+			out := outs[j]
+			out.name = tc.spec.name()
+			out.buildExpr = tc.buildExpr
+			out.deps = tc.deps
+			outs = append(outs, out)
+			continue
+		}
+		if len(targets) > 1 {
+			logger.Log(INFO, "generating target %v", tc.spec.name())
+		}
+		outs = append(outs, generate(tc, i == 0))
+	}
+	return outs
+}
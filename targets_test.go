@@ -0,0 +1,92 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/refaktor/ryegen/v2/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintSatisfied(t *testing.T) {
+	require := require.New(t)
+
+	constraint := func(s string) *config.Constraint {
+		var c config.Constraint
+		require.NoError(c.UnmarshalText([]byte(s)))
+		return &c
+	}
+
+	tags := buildTags("linux", "amd64", false, []string{"mytag"}, []string{"go1.1", "go1.2"})
+	require.Equal([]string{"linux", "amd64", "unix", "mytag", runtime.Compiler, "go1.1", "go1.2"}, tags)
+
+	require.True(constraintSatisfied(nil, tags))
+	require.True(constraintSatisfied(constraint("linux && mytag"), tags))
+	require.False(constraintSatisfied(constraint("windows || cgo"), tags))
+	require.True(constraintSatisfied(constraint("windows || cgo"), buildTags("linux", "amd64", true, nil, nil)))
+	require.True(constraintSatisfied(constraint("unix && go1.1"), tags))
+	require.False(constraintSatisfied(constraint("go1.3"), tags))
+}
+
+func TestReleaseTags(t *testing.T) {
+	require := require.New(t)
+
+	tags, err := releaseTags("go1.3.1")
+	require.NoError(err)
+	require.Equal([]string{"go1.1", "go1.2", "go1.3"}, tags)
+
+	tags, err = releaseTags("go1.25rc1")
+	require.NoError(err)
+	require.Len(tags, 25)
+	require.Equal("go1.25", tags[24])
+
+	tags, err = releaseTags("devel go1.26-0123abc Mon Jan 1 00:00:00 2026 +0000")
+	require.NoError(err)
+	require.Equal("go1.26", tags[len(tags)-1])
+
+	_, err = releaseTags("unknown")
+	require.Error(err)
+}
+
+func TestBuildTagsImplied(t *testing.T) {
+	require := require.New(t)
+
+	satisfied := func(goos, expr string) bool {
+		var c config.Constraint
+		require.NoError(c.UnmarshalText([]byte(expr)))
+		return constraintSatisfied(&c, buildTags(goos, "arm64", false, nil, nil))
+	}
+
+	require.True(satisfied("android", "linux && unix"))
+	require.True(satisfied("ios", "darwin && unix"))
+	require.True(satisfied("illumos", "solaris && unix"))
+	require.False(satisfied("linux", "android"))
+	require.False(satisfied("darwin", "ios"))
+	require.False(satisfied("windows", "unix"))
+	require.False(satisfied("js", "unix"))
+}
+
+func TestConfiguredTargets(t *testing.T) {
+	require := require.New(t)
+
+	cfg := &config.Config{Targets: []config.Target{
+		{CGoEnabled: new(bool)},
+		{GOOS: "linux", GOARCH: "amd64"},
+		{GOOS: "windows", GOARCH: "arm64", Tags: []string{"purego"}},
+		{GOOS: "linux"},
+		{GOOS: "linux", GOARCH: "amd64"},
+	}}
+	specs := configuredTargets(cfg)
+	require.Equal([]targetSpec{
+		{goos: "linux", goarch: "amd64"},
+		{goos: "windows", goarch: "arm64", tags: []string{"purego"}},
+	}, specs)
+	require.Equal("windows_arm64_purego", specs[1].name())
+
+	linux := specs[0]
+	linuxTags := buildTags(linux.goos, linux.goarch, false, linux.tags, nil)
+	require.True(targetBlockApplies(cfg.Targets[0], linux, linuxTags))
+	require.True(targetBlockApplies(cfg.Targets[1], linux, linuxTags))
+	require.False(targetBlockApplies(cfg.Targets[2], linux, linuxTags))
+	require.True(targetBlockApplies(cfg.Targets[3], linux, linuxTags))
+}
@@ -0,0 +1,13 @@
+package main
+
+func HelloWorld() string { return "Hello, world!" }
+
+func Answer() int { return 42 }
+
+type Point struct {
+	X, Y int
+}
+
+func (p Point) Total() int { return p.X + p.Y }
+
+func NewPoint(x, y int) Point { return Point{X: x, Y: y} }
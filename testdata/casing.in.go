@@ -0,0 +1,23 @@
+package main
+
+type HTTPClient struct {
+	BaseURL string
+}
+
+func NewHTTPClient(baseURL string) *HTTPClient { return &HTTPClient{BaseURL: baseURL} }
+
+func (c *HTTPClient) MaxRetries() int { return 3 }
+
+func (c *HTTPClient) Handle() string { return "handled by " + c.BaseURL }
+
+type Handler interface {
+	Handle() string
+}
+
+func DefaultHandler() Handler { return &HTTPClient{BaseURL: "default"} }
+
+func Version() string { return "v1" }
+
+func Int64Max() int64 { return 1<<63 - 1 }
+
+func Base64Encode(s string) string { return "encoded " + s }
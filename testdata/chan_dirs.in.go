@@ -0,0 +1,51 @@
+package main
+
+import "fmt"
+
+// To Rye, receive-only
+func Count(n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		for i := range n {
+			ch <- i
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// To Rye, send-only
+func Sink(done chan<- struct{}) chan<- string {
+	ch := make(chan string)
+	go func() {
+		for s := range ch {
+			fmt.Println("sink:", s)
+		}
+		done <- struct{}{}
+	}()
+	return ch
+}
+
+// From Rye, send-only
+func Fill(ch chan<- int, n int) {
+	for i := range n {
+		ch <- 10 * i
+	}
+	close(ch)
+}
+
+// From Rye, receive-only
+func Sum(ch <-chan int, done chan<- struct{}) {
+	sum := 0
+	for i := range ch {
+		sum += i
+	}
+	fmt.Println("sum:", sum)
+	done <- struct{}{}
+}
+
+// From Rye, bidirectional
+func Relay(ch chan int) {
+	v := <-ch
+	ch <- v + 1
+}
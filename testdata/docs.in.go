@@ -0,0 +1,17 @@
+package main
+
+// Hello returns a greeting.
+//
+// This paragraph is left out of the doc string.
+func Hello(name string) string {
+	return "Hello, " + name
+}
+
+// Celsius is a temperature.
+type Celsius float64
+
+// Point is a point in 2D space.
+type Point struct {
+	X, Y int
+	Name string
+}
@@ -0,0 +1,13 @@
+package main
+
+func HTTPGet() string { return "HTTPGet" }
+
+func HttpGet() string { return "HttpGet" }
+
+type Page struct {
+	Title string
+}
+
+func (p Page) GetTitle() string { return "method: " + p.Title }
+
+func NewPage(title string) Page { return Page{Title: title} }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Point struct {
+	X, Y int
+}
+
+type Shape struct {
+	Name   string
+	Points []Point
+	Tags   map[string]string
+}
+
+func NewSquare(size int) Shape {
+	return Shape{
+		Name:   "square",
+		Points: []Point{{0, 0}, {size, 0}, {size, size}, {0, size}},
+		Tags:   map[string]string{"kind": "regular"},
+	}
+}
+
+func PrintShape(s Shape) {
+	fmt.Printf("%v %v %v\n", s.Name, s.Points, s.Tags)
+}
+
+func (s *Shape) Move(dx, dy int) {
+	for i := range s.Points {
+		s.Points[i].X += dx
+		s.Points[i].Y += dy
+	}
+}
+
+func Sum(xs []int) int {
+	res := 0
+	for _, x := range xs {
+		res += x
+	}
+	return res
+}
+
+func Words(s string) []string {
+	return strings.Fields(s)
+}
+
+func Divide(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func Apply(f func(int, int) int, a, b int) int {
+	return f(a, b)
+}
+
+func Adder(n int) func(int) int {
+	return func(x int) int {
+		return x + n
+	}
+}
+
+func Describe(x any) {
+	fmt.Printf("%T %v\n", x, x)
+}
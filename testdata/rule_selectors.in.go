@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+type Ctx struct{}
+
+func Background() Ctx { return Ctx{} }
+
+func WithCtx(ctx Ctx, s string) string { return "with ctx: " + s }
+
+func Repeat(s string, n int) string { return strings.Repeat(s, n) }
+
+func Sum(xs ...int) int {
+	acc := 0
+	for _, x := range xs {
+		acc += x
+	}
+	return acc
+}
+
+func Half(n int) (int, error) {
+	if n%2 != 0 {
+		return 0, errors.New("odd number")
+	}
+	return n / 2, nil
+}
+
+func Greeting() string { return "hello" }
+
+func Uintptr(p uintptr) {}
+
+func MakeChan() chan int { return nil }
+
+// OldGreeting returns a greeting.
+//
+// Deprecated: Use Greeting instead.
+func OldGreeting() string { return "hi" }
+
+// Answer returns the answer to everything.
+func Answer() int { return 42 }
@@ -0,0 +1,126 @@
+package textutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Casings supported by [ToCasing].
+var Casings = []string{"kebab", "camel", "lower-camel", "snake", "screaming-snake"}
+
+// Splits the identifier-like string s into words.
+// Words are separated by '-', '_' and case changes from lower
+// case or digits to upper case. If splitDigits is set, runs of
+// digits are words of their own, e.g. "MaxInt64" -> ["Max",
+// "Int", "64"]. Otherwise they stay with the word before them,
+// e.g. "MaxInt64" -> ["Max", "Int64"] and "h2c" -> ["h2c"].
+// Runs of upper case letters are kept together as acronyms,
+// except for the last one if it starts a new word, e.g.
+// "HTTPClient" -> ["HTTP", "Client"]. A single lower case
+// letter following an acronym is kept with it if it is a plural
+// 's' or followed by a digit, e.g. "URLs" -> ["URLs"] and
+// "IPv4" -> ["IPv4"].
+func SplitWords(s string, splitDigits bool) []string {
+	rs := []rune(s)
+	isUpper := func(i int) bool { return i >= 0 && i < len(rs) && unicode.IsUpper(rs[i]) }
+	isLower := func(i int) bool { return i >= 0 && i < len(rs) && unicode.IsLower(rs[i]) }
+	isDigit := func(i int) bool { return i >= 0 && i < len(rs) && unicode.IsDigit(rs[i]) }
+
+	var res []string
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			res = append(res, string(rs[start:end]))
+		}
+		start = end
+	}
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == '-' || rs[i] == '_':
+			flush(i)
+			start = i + 1
+		case splitDigits && isDigit(i) != isDigit(i-1) && i > start:
+			flush(i)
+		case isUpper(i) && (isLower(i-1) || isDigit(i-1)):
+			flush(i)
+		case isUpper(i) && isUpper(i-1) && isLower(i+1):
+			if rs[i+1] == 's' && !isLower(i+2) {
+				// Plural acronym, e.g. "URLs"
+				i++
+				continue
+			}
+			if isDigit(i + 2) {
+				// Versioned acronym, e.g. "IPv4"
+				i++
+				continue
+			}
+			flush(i)
+		}
+	}
+	flush(len(rs))
+	return res
+}
+
+// Converts s to the given casing (one of [Casings]), splitting
+// it into words with [SplitWords]. Only runs of letters, digits,
+// '-' and '_' are converted; all other characters are kept as
+// they are, e.g. ToCasing("HTTPClient?", "kebab", false) =
+// "http-client?" and ToCasing("net/http_util", "camel", false) =
+// "Net/HttpUtil". Returns false if the casing is unknown.
+func ToCasing(s, casing string, splitDigits bool) (string, bool) {
+	var sep string
+	var convWord func(w string, first bool) string
+	title := func(w string) string {
+		rs := []rune(strings.ToLower(w))
+		rs[0] = unicode.ToUpper(rs[0])
+		return string(rs)
+	}
+	switch casing {
+	case "kebab":
+		sep = "-"
+		convWord = func(w string, _ bool) string { return strings.ToLower(w) }
+	case "snake":
+		sep = "_"
+		convWord = func(w string, _ bool) string { return strings.ToLower(w) }
+	case "screaming-snake":
+		sep = "_"
+		convWord = func(w string, _ bool) string { return strings.ToUpper(w) }
+	case "camel":
+		convWord = func(w string, _ bool) string { return title(w) }
+	case "lower-camel":
+		convWord = func(w string, first bool) string {
+			if first {
+				return strings.ToLower(w)
+			}
+			return title(w)
+		}
+	default:
+		return "", false
+	}
+
+	isWordChar := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'
+	}
+
+	var res strings.Builder
+	for len(s) > 0 {
+		end := strings.IndexFunc(s, func(r rune) bool { return !isWordChar(r) })
+		if end == -1 {
+			end = len(s)
+		}
+		for i, w := range SplitWords(s[:end], splitDigits) {
+			if i > 0 {
+				res.WriteString(sep)
+			}
+			res.WriteString(convWord(w, i == 0))
+		}
+		s = s[end:]
+		end = strings.IndexFunc(s, isWordChar)
+		if end == -1 {
+			end = len(s)
+		}
+		res.WriteString(s[:end])
+		s = s[end:]
+	}
+	return res.String(), true
+}
@@ -50,3 +50,41 @@ func IndentString(s string, indent string, nIndent int) string {
 
 	return res.String()
 }
+
+// Returns the first paragraph of s with all of its
+// lines joined by a single space.
+// Paragraphs are separated by lines that are empty
+// or contain only spaces.
+func FirstParagraph(s string) string {
+	var words []string
+	for line := range strings.Lines(s) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if len(words) > 0 {
+				break
+			}
+			continue
+		}
+		words = append(words, fields...)
+	}
+	return strings.Join(words, " ")
+}
+
+// Returns whether the Go doc comment s marks its symbol
+// as deprecated, i.e. whether any of its paragraphs
+// starts with "Deprecated: ".
+func IsDeprecated(s string) bool {
+	paragraphStart := true
+	for line := range strings.Lines(s) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			paragraphStart = true
+			continue
+		}
+		if paragraphStart && strings.HasPrefix(line, "Deprecated: ") {
+			return true
+		}
+		paragraphStart = false
+	}
+	return false
+}
@@ -41,3 +41,104 @@ World
 `, "  ", 1),
 	)
 }
+
+func TestFirstParagraph(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("", FirstParagraph(""))
+	require.Equal("Hello World", FirstParagraph("Hello World"))
+	require.Equal("Hello World", FirstParagraph("  Hello\n\tWorld\n"))
+	require.Equal("Hello World", FirstParagraph(`
+  
+Hello
+World
+
+Second paragraph.
+`))
+}
+
+func TestIsDeprecated(t *testing.T) {
+	require := require.New(t)
+
+	require.False(IsDeprecated(""))
+	require.False(IsDeprecated("Foo does bar."))
+	require.True(IsDeprecated("Deprecated: Use Bar instead."))
+	require.True(IsDeprecated("Foo does bar.\n\nDeprecated: Use Bar instead.\n"))
+	require.True(IsDeprecated("Foo does bar.\n  \n  Deprecated: Use Bar\n  instead."))
+	// Must be at the start of a paragraph
+	require.False(IsDeprecated("Foo does bar.\nDeprecated: Use Bar instead."))
+	require.False(IsDeprecated("Foo is not Deprecated: really."))
+	require.False(IsDeprecated("Deprecated:Use Bar instead."))
+}
+
+func TestSplitWords(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal([]string{"HTTP", "Client"}, SplitWords("HTTPClient", false))
+	require.Equal([]string{"New", "HTTP", "Client"}, SplitWords("NewHTTPClient", false))
+	require.Equal([]string{"parse", "URLs"}, SplitWords("parseURLs", false))
+	require.Equal([]string{"IDs", "Of"}, SplitWords("IDsOf", false))
+	require.Equal([]string{"Max", "Int64"}, SplitWords("MaxInt64", false))
+	require.Equal([]string{"Int64", "Slice"}, SplitWords("Int64Slice", false))
+	require.Equal([]string{"Parse", "IPv4"}, SplitWords("ParseIPv4", false))
+	require.Equal([]string{"v2"}, SplitWords("v2", false))
+	require.Equal([]string{"h2c"}, SplitWords("h2c", false))
+	require.Equal([]string{"get", "foo", "bar"}, SplitWords("get-foo_bar", false))
+	require.Equal([]string{"MAX", "VALUE"}, SplitWords("MAX_VALUE", false))
+	require.Empty(SplitWords("_-", false))
+
+	// Digits as words of their own
+	require.Equal([]string{"Max", "Int", "64"}, SplitWords("MaxInt64", true))
+	require.Equal([]string{"Process", "5", "Ints"}, SplitWords("Process5Ints", true))
+	require.Equal([]string{"h", "2", "c"}, SplitWords("h2c", true))
+	require.Equal([]string{"HTTP", "Client"}, SplitWords("HTTPClient", true))
+}
+
+func TestToCasing(t *testing.T) {
+	require := require.New(t)
+
+	conv := func(s, casing string) string {
+		res, ok := ToCasing(s, casing, false)
+		require.True(ok)
+		return res
+	}
+	convSplit := func(s, casing string) string {
+		res, ok := ToCasing(s, casing, true)
+		require.True(ok)
+		return res
+	}
+
+	require.Equal("http-client", conv("HTTPClient", "kebab"))
+	require.Equal("HttpClient", conv("HTTPClient", "camel"))
+	require.Equal("httpClient", conv("HTTPClient", "lower-camel"))
+	require.Equal("http_client", conv("HTTPClient", "snake"))
+	require.Equal("HTTP_CLIENT", conv("HTTPClient", "screaming-snake"))
+	require.Equal("MAX_INT64", conv("MaxInt64", "screaming-snake"))
+	require.Equal("max-int64", conv("MaxInt64", "kebab"))
+	require.Equal("parse-ipv4", conv("ParseIPv4", "kebab"))
+	require.Equal("PARSE_IPV4", conv("ParseIPv4", "screaming-snake"))
+	require.Equal("parse-urls", conv("ParseURLs", "kebab"))
+
+	// Non-word characters are kept
+	require.Equal("name?", conv("Name?", "kebab"))
+	require.Equal("Name!", conv("name!", "camel"))
+	require.Equal(`http-get\main`, conv(`HTTPGet\main`, "kebab"))
+	require.Equal("github.com/fyneIo/fyne/v2", conv("github.com/fyne-io/fyne/v2", "lower-camel"))
+	require.Equal("*netHttp.client", conv("*net_http.Client", "lower-camel"))
+	require.Equal("fyne.io/fyne/v2", conv("fyne.io/fyne/v2", "kebab"))
+	require.Equal("golang.org/x/net/http2/h2c", conv("golang.org/x/net/http2/h2c", "kebab"))
+	require.Equal("golang.org/x/net/http2/h2c", conv("golang.org/x/net/http2/h2c", "lower-camel"))
+	require.Equal("GOLANG.ORG/X/NET/HTTP2/H2C", conv("golang.org/x/net/http2/h2c", "screaming-snake"))
+
+
+	// Digits as words of their own
+	require.Equal("process-5-ints", convSplit("Process5Ints", "kebab"))
+	require.Equal("new_t_2", convSplit("NewT2", "snake"))
+	require.Equal("Cmplx64", convSplit("cmplx64", "camel"))
+	require.Equal("MAX_INT_64", convSplit("MaxInt64", "screaming-snake"))
+	require.Equal("funcTakingT1Ptr", convSplit("func-taking-t-1-ptr", "lower-camel"))
+	require.Equal("http-client", convSplit("HTTPClient", "kebab"))
+
+	_, ok := ToCasing("x", "pascal", false)
+	require.False(ok)
+}